@@ -2,9 +2,11 @@ package cmd
 
 import (
 	"fmt"
+	"math"
+	"strconv"
+	"time"
 
 	"rime-wanxiang-logger-go/internal/analyzer"
-	"rime-wanxiang-logger-go/internal/manager"
 	"rime-wanxiang-logger-go/internal/ui"
 
 	"github.com/spf13/cobra"
@@ -15,43 +17,88 @@ var analyzeCmd = &cobra.Command{
 	Use:   "analyze",
 	Short: "Analyze the collected log data.",
 	Long: `This command reads the JSONL log file, calculates various metrics such as
-prediction accuracy (first choice hit rate, top-3 hit rate), and displays the results.`,
+prediction accuracy (first choice hit rate, top-3 hit rate), and displays the results.
+Use --by hour|day|week|month to also show how accuracy changes over time, or
+--by device to compare the devices of a log combined with 'merge' (read it
+with --log).`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ui.Section("输入习惯分析")
 
 		// Initialize RimeManager to get the correct log file path
-		rimeManager, err := manager.NewRimeManager()
+		rimeManager, err := newRimeManager()
 		if err != nil {
 			return fmt.Errorf("failed to initialize Rime manager: %w", err)
 		}
 
 		// Get the actual log file path by parsing the config
-		logFilePath, err := rimeManager.GetLogFilePath()
+		logFilePath, logFiles, err := logSources(cmd, rimeManager)
 		if err != nil {
 			return fmt.Errorf("failed to determine log file path: %w", err)
 		}
 
 		// Check if log file exists
-		if !rimeManager.LogFileExists() {
+		if len(logFiles) == 0 {
 			ui.Errorf("未找到日志文件: %s", logFilePath)
 			return nil
 		}
 
-		ui.Infof("正在分析日志文件: %s", logFilePath)
+		// Optional time-bucketed trend or per-device analysis
+		var trends *analyzer.TrendTracker
+		var devices *analyzer.DeviceTracker
+		if by, _ := cmd.Flags().GetString("by"); by == byDevice {
+			devices = analyzer.NewDeviceTracker()
+		} else if by != "" {
+			granularity, err := analyzer.ParseGranularity(by)
+			if err != nil {
+				return err
+			}
+			trends = analyzer.NewTrendTracker(granularity)
+		}
 
-		// Read and parse the log file
-		events, err := analyzer.ReadLogFile(logFilePath)
+		scanOpts, err := logScanOptions(cmd)
 		if err != nil {
-			return fmt.Errorf("分析过程中发生错误: %w", err)
+			return err
 		}
 
-		if len(events) == 0 {
-			ui.Warnf("日志文件中未找到 'text_committed' 事件。")
+		ui.Infof("正在分析日志文件: %s", describeLogSources(logFilePath, logFiles))
+
+		// Stream the log file through the accumulator
+		acc := analyzer.NewAccumulator()
+		dist := analyzer.NewDistributionTracker()
+		err = analyzer.ScanCommits(logFiles, scanOpts, func(event *analyzer.TextCommittedEvent) error {
+			acc.Add(event)
+			dist.Add(event)
+			if trends != nil {
+				trends.Add(event)
+			}
+			if devices != nil {
+				devices.Add(event)
+			}
 			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("分析过程中发生错误: %w", err)
 		}
 
 		// Perform comprehensive analysis matching Python version
-		results := analyzer.PerformAnalysis(events)
+		results := acc.Result()
+
+		if ui.Structured() {
+			report := analyzeReport{LogFile: logFilePath, Result: results, Distribution: dist.Result()}
+			if trends != nil {
+				report.Granularity = string(trends.Granularity())
+				report.Trends = trends.Buckets()
+			}
+			if devices != nil {
+				report.Devices = devices.Devices()
+			}
+			return ui.Emit(report)
+		}
+
+		if !results.HasCommits {
+			ui.Warnf("日志文件中未找到 'text_committed' 事件。")
+			return nil
+		}
 
 		// Display prediction accuracy metrics
 		ui.Subsection("预测准确度指标")
@@ -75,10 +122,222 @@ prediction accuracy (first choice hit rate, top-3 hit rate), and displays the re
 			ui.PrintKV([][2]string{{"直接上屏率 (非候选词)", fmt.Sprintf("%.2f%%", results.DirectInputRate)}})
 		}
 
+		printDistribution(dist.Result())
+
+		if trends != nil {
+			printTrends(trends)
+		}
+		if devices != nil {
+			printDevices(devices.Devices())
+		}
+
 		return nil
 	},
 }
 
+// analyzeReport is the structured (--format json|yaml|csv) form of the analyze output.
+type analyzeReport struct {
+	LogFile      string                  `json:"log_file" yaml:"log_file"`
+	Result       analyzer.AnalysisResult `json:"result" yaml:"result"`
+	Distribution analyzer.Distribution   `json:"distribution" yaml:"distribution"`
+	Granularity  string                  `json:"granularity,omitempty" yaml:"granularity,omitempty"`
+	Trends       []analyzer.TrendBucket  `json:"trends,omitempty" yaml:"trends,omitempty"`
+	Devices      []analyzer.DeviceResult `json:"devices,omitempty" yaml:"devices,omitempty"`
+}
+
+// byDevice is the --by value grouping commits by the device_id of a merged log.
+const byDevice = "device"
+
+// Table implements ui.Tabular: one row per trend bucket or device, or a single row for
+// the whole log extended with rank_<n>, page_<n> and method_<name> count columns.
+func (r analyzeReport) Table() ([]string, [][]string) {
+	if len(r.Devices) > 0 {
+		headers := append([]string{"device"}, analysisResultColumns()...)
+		rows := make([][]string, 0, len(r.Devices))
+		for _, d := range r.Devices {
+			rows = append(rows, append([]string{d.Device}, analysisResultRow(d.Result)...))
+		}
+		return headers, rows
+	}
+	if len(r.Trends) == 0 {
+		headers := analysisResultColumns()
+		row := analysisResultRow(r.Result)
+		for _, rc := range r.Distribution.Ranks {
+			headers = append(headers, fmt.Sprintf("rank_%d", rc.Rank))
+			row = append(row, strconv.Itoa(rc.Count))
+		}
+		for _, pc := range r.Distribution.Pages {
+			headers = append(headers, fmt.Sprintf("page_%d", pc.Page))
+			row = append(row, strconv.Itoa(pc.Count))
+		}
+		for _, mc := range r.Distribution.SelectionMethods {
+			headers = append(headers, "method_"+mc.Method)
+			row = append(row, strconv.Itoa(mc.Count))
+		}
+		return headers, [][]string{row}
+	}
+	headers := append([]string{"bucket", "start"}, analysisResultColumns()...)
+	rows := make([][]string, 0, len(r.Trends))
+	for _, b := range r.Trends {
+		row := append([]string{b.Label, b.Start.Format(time.RFC3339)}, analysisResultRow(b.Result)...)
+		rows = append(rows, row)
+	}
+	return headers, rows
+}
+
+// analysisResultColumns lists the CSV column names of an AnalysisResult,
+// matching its JSON field names.
+func analysisResultColumns() []string {
+	return []string{
+		"total_commits", "total_selections", "raw_input_commits",
+		"first_choice_count", "top3_count", "first_choice_hit_rate", "top3_hit_rate",
+		"average_rank", "overall_accuracy_score", "direct_input_rate",
+	}
+}
+
+func analysisResultRow(r analyzer.AnalysisResult) []string {
+	return []string{
+		strconv.Itoa(r.TotalCommits),
+		strconv.Itoa(r.TotalSelections),
+		strconv.Itoa(r.RawInputCommits),
+		strconv.Itoa(r.FirstChoiceCount),
+		strconv.Itoa(r.Top3Count),
+		strconv.FormatFloat(r.FirstChoiceHitRate, 'f', 4, 64),
+		strconv.FormatFloat(r.Top3HitRate, 'f', 4, 64),
+		strconv.FormatFloat(r.AverageRank, 'f', 4, 64),
+		strconv.FormatFloat(r.OverallAccuracyScore, 'f', 4, 64),
+		strconv.FormatFloat(r.DirectInputRate, 'f', 4, 64),
+	}
+}
+
+// printDistribution renders the rank, page and selection method breakdowns as bar charts.
+func printDistribution(d analyzer.Distribution) {
+	if len(d.Ranks) > 0 {
+		ui.Subsection("选择排名分布")
+		var bars []ui.Bar
+		for _, rc := range d.Ranks {
+			bars = append(bars, ui.Bar{
+				Label: fmt.Sprintf("排名 %d", rc.Rank),
+				Value: float64(rc.Count),
+				Note:  fmt.Sprintf("%d (%.2f%%)", rc.Count, rc.Percent),
+			})
+		}
+		ui.PrintBarChart(bars, 40)
+
+		ui.Subsection(fmt.Sprintf("翻页分布 (每页 %d 个候选)", analyzer.PageSize))
+		bars = nil
+		for _, pc := range d.Pages {
+			bars = append(bars, ui.Bar{
+				Label: fmt.Sprintf("第 %d 页 (排名 %d-%d)", pc.Page, pc.FirstRank, pc.LastRank),
+				Value: float64(pc.Count),
+				Note:  fmt.Sprintf("%d (%.2f%%)", pc.Count, pc.Percent),
+			})
+		}
+		ui.PrintBarChart(bars, 40)
+	}
+
+	if len(d.SelectionMethods) > 0 {
+		ui.Subsection("选择方式分布")
+		var bars []ui.Bar
+		for _, mc := range d.SelectionMethods {
+			bars = append(bars, ui.Bar{
+				Label: mc.Method,
+				Value: float64(mc.Count),
+				Note:  fmt.Sprintf("%d (%.2f%%)", mc.Count, mc.Percent),
+			})
+		}
+		ui.PrintBarChart(bars, 40)
+	}
+}
+
+// printTrends renders the per-bucket accuracy table followed by sparklines.
+func printTrends(trends *analyzer.TrendTracker) {
+	ui.Subsection("准确度趋势")
+
+	buckets := trends.Buckets()
+	if len(buckets) == 0 {
+		ui.Warnf("没有带时间戳的上屏记录，无法计算趋势。")
+		return
+	}
+
+	headers := []string{"时间段", "候选词选择数", "首选命中率", "前三候选命中率", "平均选择排名", "综合预测得分"}
+	var rows [][]string
+	var firstChoice, top3, score []float64
+	for _, b := range buckets {
+		r := b.Result
+		if !r.HasValidSelections {
+			rows = append(rows, []string{b.Label, "0", "-", "-", "-", "-"})
+			firstChoice = append(firstChoice, math.NaN())
+			top3 = append(top3, math.NaN())
+			score = append(score, math.NaN())
+			continue
+		}
+		rows = append(rows, []string{
+			b.Label,
+			fmt.Sprintf("%d", r.TotalSelections),
+			fmt.Sprintf("%.2f%%", r.FirstChoiceHitRate),
+			fmt.Sprintf("%.2f%%", r.Top3HitRate),
+			fmt.Sprintf("%.2f", r.AverageRank),
+			fmt.Sprintf("%.3f", r.OverallAccuracyScore),
+		})
+		firstChoice = append(firstChoice, r.FirstChoiceHitRate)
+		top3 = append(top3, r.Top3HitRate)
+		score = append(score, r.OverallAccuracyScore)
+	}
+	ui.PrintTable(headers, rows)
+
+	ui.Printf("\n")
+	ui.PrintKV([][2]string{
+		{"首选命中率", ui.Sparkline(firstChoice)},
+		{"前三候选命中率", ui.Sparkline(top3)},
+		{"综合预测得分", ui.Sparkline(score)},
+	})
+
+	if n := trends.Untimed(); n > 0 {
+		ui.Warnf("%d 条上屏记录缺少时间戳，未计入趋势。", n)
+	}
+}
+
+// printDevices renders the accuracy of each device of a merged log.
+func printDevices(devices []analyzer.DeviceResult) {
+	ui.Subsection("各设备准确度")
+
+	headers := []string{"设备", "上屏次数", "候选词选择数", "首选命中率", "前三候选命中率", "平均选择排名", "综合预测得分"}
+	var rows [][]string
+	for _, d := range devices {
+		device := deviceLabel(d.Device)
+		r := d.Result
+		if !r.HasValidSelections {
+			rows = append(rows, []string{device, fmt.Sprintf("%d", r.TotalCommits), "0", "-", "-", "-", "-"})
+			continue
+		}
+		rows = append(rows, []string{
+			device,
+			fmt.Sprintf("%d", r.TotalCommits),
+			fmt.Sprintf("%d", r.TotalSelections),
+			fmt.Sprintf("%.2f%%", r.FirstChoiceHitRate),
+			fmt.Sprintf("%.2f%%", r.Top3HitRate),
+			fmt.Sprintf("%.2f", r.AverageRank),
+			fmt.Sprintf("%.3f", r.OverallAccuracyScore),
+		})
+	}
+	ui.PrintTable(headers, rows)
+
+	if len(devices) == 1 && devices[0].Device == "" {
+		ui.Warnf("日志中的记录没有设备标记。请先用 'merge' 合并各设备的日志，再用 --log 读取合并结果。")
+	}
+}
+
+// deviceLabel names the device_id of a merged log entry for display.
+func deviceLabel(device string) string {
+	if device == "" {
+		return "(未标记)"
+	}
+	return device
+}
+
 func init() {
 	rootCmd.AddCommand(analyzeCmd)
+	addLogReadFlags(analyzeCmd)
+	analyzeCmd.Flags().String("by", "", "按时间段统计准确度趋势: hour|day|week|month；或按设备统计: device")
 }
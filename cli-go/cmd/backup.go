@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"rime-wanxiang-logger-go/internal/manager"
+	"rime-wanxiang-logger-go/internal/ui"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+)
+
+// backupCmd represents the backup command
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "List and restore backups of files changed by install and uninstall.",
+	Long: `Before install or uninstall changes a file in the Rime user directory
+(schema files, *.custom.yaml patches, the Lua scripts), the original is copied
+into a timestamped backup under input_habit_logger_backups/. Each backup has a
+manifest recording the original path, sha256, tool version and reason.`,
+}
+
+// backupListCmd represents the backup list command
+var backupListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List all backups, newest first.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ui.Section("备份列表")
+
+		rimeManager, err := newRimeManager()
+		if err != nil {
+			return fmt.Errorf("failed to initialize Rime manager: %w", err)
+		}
+
+		backups, err := rimeManager.ListBackups()
+		if err != nil {
+			return err
+		}
+
+		if ui.Structured() {
+			return ui.Emit(backupListReport{BackupDirectory: rimeManager.GetBackupDirectory(), Backups: backups})
+		}
+
+		if len(backups) == 0 {
+			ui.Infof("尚无备份: %s", rimeManager.GetBackupDirectory())
+			return nil
+		}
+
+		headers := []string{"ID", "时间", "原因", "工具版本", "文件"}
+		var rows [][]string
+		for _, b := range backups {
+			for i, f := range b.Files {
+				row := []string{"", "", "", "", backupFileLabel(f)}
+				if i == 0 {
+					row = []string{b.ID, formatLocalTime(b.CreatedAt), b.Reason, b.ToolVersion, backupFileLabel(f)}
+				}
+				rows = append(rows, row)
+			}
+		}
+		ui.PrintTable(headers, rows)
+		ui.Infof("备份目录: %s", rimeManager.GetBackupDirectory())
+		return nil
+	},
+}
+
+// backupRestoreCmd represents the backup restore command
+var backupRestoreCmd = &cobra.Command{
+	Use:   "restore <id>",
+	Short: "Restore every file of a backup.",
+	Long: `This command puts the files of a backup back in place, after checking
+them against the sha256 in the manifest. Files that did not exist when the
+backup was taken are removed. The current files are backed up first, so a
+restore can itself be undone.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ui.Section("恢复备份")
+
+		assumeYes, _ := cmd.Flags().GetBool("yes")
+
+		rimeManager, err := newRimeManager()
+		if err != nil {
+			return fmt.Errorf("failed to initialize Rime manager: %w", err)
+		}
+
+		backup, err := rimeManager.GetBackup(args[0])
+		if errors.Is(err, manager.ErrBackupNotFound) {
+			return fmt.Errorf("%w (run 'backup list' to see available IDs)", err)
+		}
+		if err != nil {
+			return err
+		}
+
+		ui.Infof("备份 %s (%s，%s):", backup.ID, backup.Reason, formatLocalTime(backup.CreatedAt))
+		for _, f := range backup.Files {
+			ui.Infof("  %s", backupFileLabel(f))
+		}
+
+		if !assumeYes {
+			if !isInteractive() {
+				return errNotInteractive
+			}
+			confirm := promptui.Prompt{
+				Label:     "是否用该备份覆盖以上文件？",
+				IsConfirm: true,
+			}
+			if _, err := confirm.Run(); err != nil {
+				ui.Infof("Restore cancelled.")
+				return nil
+			}
+		}
+
+		undo, err := rimeManager.RestoreBackup(backup.ID)
+		if err != nil {
+			return fmt.Errorf("failed to restore backup: %w", err)
+		}
+
+		ui.Successf("已恢复备份 %s。", backup.ID)
+		if id := undo.ID(); id != "" {
+			ui.Infof("恢复前的文件已备份 (ID: %s)。", id)
+		}
+		ui.Warnf("重要提示: 您必须立即 '重新部署' Rime才能使更改生效。")
+		return nil
+	},
+}
+
+// backupListReport is the structured (--format json|yaml|csv) form of the backup list output.
+type backupListReport struct {
+	BackupDirectory string           `json:"backup_directory" yaml:"backup_directory"`
+	Backups         []manager.Backup `json:"backups" yaml:"backups"`
+}
+
+// Table implements ui.Tabular with one row per backed up file.
+func (r backupListReport) Table() ([]string, [][]string) {
+	var rows [][]string
+	for _, b := range r.Backups {
+		for _, f := range b.Files {
+			rows = append(rows, []string{
+				b.ID,
+				b.CreatedAt.Format(time.RFC3339),
+				b.Reason,
+				b.ToolVersion,
+				f.OriginalPath,
+				f.SHA256,
+				strconv.FormatInt(f.Size, 10),
+				strconv.FormatBool(f.Absent),
+			})
+		}
+	}
+	return []string{"id", "created_at", "reason", "tool_version", "original_path", "sha256", "size", "absent"}, rows
+}
+
+func backupFileLabel(f manager.BackupFile) string {
+	if f.Absent {
+		return f.OriginalPath + " (原本不存在)"
+	}
+	return f.OriginalPath
+}
+
+func init() {
+	rootCmd.AddCommand(backupCmd)
+	backupCmd.AddCommand(backupListCmd, backupRestoreCmd)
+
+	backupRestoreCmd.Flags().BoolP("yes", "y", false, "不进行确认，直接恢复")
+}
@@ -0,0 +1,225 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"rime-wanxiang-logger-go/internal/assets"
+	"rime-wanxiang-logger-go/internal/manager"
+	"rime-wanxiang-logger-go/internal/ui"
+
+	"github.com/spf13/cobra"
+)
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Show and change the logger configuration.",
+	Long: `These commands read and edit input_habit_logger_config.lua without
+hand-editing Lua. Keys are:
+
+  preset                                         the active preset (preset_choice)
+  enabled, log_only_non_first_choice             booleans
+  log_file_path                                  a path, or "" for the default
+  log_events.<event>                             booleans
+  log_fields.<event>.<field>                     booleans
+  log_fields.input_state_changed.event_subtype.<subtype>
+
+Events, fields and subtypes are checked against the ones the logger writes.`,
+}
+
+// configShowCmd represents the config show command
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the effective configuration.",
+	Long: `This command evaluates input_habit_logger_config.lua the way the logger
+does, merges it with the logger's defaults and prints the result.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ui.Section("日志记录器配置")
+
+		rimeManager, err := newRimeManager()
+		if err != nil {
+			return fmt.Errorf("failed to initialize Rime manager: %w", err)
+		}
+
+		config, loadErr := rimeManager.LoadLoggerConfig()
+		if loadErr != nil {
+			ui.Warnf("无法加载配置文件，记录器将使用默认配置。错误: %v", loadErr)
+		}
+		logFilePath, _ := rimeManager.GetLogFilePath()
+
+		if ui.Structured() {
+			return ui.Emit(configReport{ConfigPath: rimeManager.GetConfigPath(), LogFile: logFilePath, Config: config})
+		}
+
+		preset := config.Preset
+		if config.PresetChoice != "" && config.PresetChoice != config.Preset {
+			preset = fmt.Sprintf("%s (preset_choice '%s' 无效，已回退)", config.Preset, config.PresetChoice)
+		}
+		ui.PrintKV([][2]string{
+			{"配置文件", rimeManager.GetConfigPath()},
+			{"预设", preset},
+			{"enabled", strconv.FormatBool(config.Enabled)},
+			{"log_only_non_first_choice", strconv.FormatBool(config.LogOnlyNonFirstChoice)},
+			{"日志文件", logFilePath},
+		})
+
+		ui.Subsection("log_events")
+		var rows [][]string
+		for _, event := range sortedConfigKeys(config.LogEvents, manager.LogEvents) {
+			rows = append(rows, []string{event, onOff(config.LogEvents[event])})
+		}
+		ui.PrintTable([]string{"事件", "记录"}, rows)
+
+		ui.Subsection("log_fields")
+		rows = nil
+		for _, event := range manager.LogEvents {
+			fields := config.LogFields[event]
+			for _, field := range sortedConfigKeys(fields, manager.LogEventFields[event]) {
+				rows = append(rows, []string{event, field, onOff(fields[field])})
+			}
+		}
+		for _, subtype := range sortedConfigKeys(config.EventSubtypes, manager.InputStateSubtypes) {
+			rows = append(rows, []string{"input_state_changed", "event_subtype." + subtype, onOff(config.EventSubtypes[subtype])})
+		}
+		ui.PrintTable([]string{"事件", "字段", "记录"}, rows)
+		return nil
+	},
+}
+
+// configGetCmd represents the config get command
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print the effective value of one key.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rimeManager, err := newRimeManager()
+		if err != nil {
+			return fmt.Errorf("failed to initialize Rime manager: %w", err)
+		}
+
+		config, loadErr := rimeManager.LoadLoggerConfig()
+		if loadErr != nil {
+			ui.Warnf("无法加载配置文件，记录器将使用默认配置。错误: %v", loadErr)
+		}
+		value, err := config.ConfigValue(args[0])
+		if err != nil {
+			return err
+		}
+
+		if ui.Structured() {
+			return ui.Emit(manager.ConfigSetting{Key: args[0], Value: value})
+		}
+		fmt.Println(value)
+		return nil
+	},
+}
+
+// configSetCmd represents the config set command
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Change one key in input_habit_logger_config.lua.",
+	Long: `This command rewrites the value of one key in input_habit_logger_config.lua,
+keeping every comment and the rest of the file unchanged. Keys other than
+preset are changed in the active preset's table, or in the one given with
+--preset. Tables missing from the preset are created. The file is backed up
+first, and --dry-run prints the change without writing it.
+
+Examples:
+  config set preset advanced
+  config set log_fields.text_committed.committed_text false
+  config set log_file_path /home/me/rime_log.jsonl`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ui.Section("修改日志记录器配置")
+
+		preset, _ := cmd.Flags().GetString("preset")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		if preset != "" && !manager.IsValidPreset(preset) {
+			return fmt.Errorf("unknown preset %q (expected one of: %v)", preset, manager.Presets)
+		}
+
+		rimeManager, err := newRimeManager()
+		if err != nil {
+			return fmt.Errorf("failed to initialize Rime manager: %w", err)
+		}
+
+		plan := manager.NewPlan()
+		change, err := rimeManager.PlanConfigSet(plan, assets.ConfigScript, args[0], args[1], preset)
+		if err != nil {
+			return err
+		}
+
+		target := change.Key
+		if change.Preset != "" {
+			target = "presets." + change.Preset + "." + change.Key
+		}
+		old := change.Old
+		if old == "" {
+			old = "(未设置)"
+		}
+		ui.Infof("%s: %s → %s", target, old, change.New)
+
+		if dryRun {
+			return showPlan(plan)
+		}
+		if plan.Empty() {
+			ui.Infof("配置未发生变化。")
+			return nil
+		}
+
+		backup, err := applyPlan(rimeManager, plan, "config set")
+		if err != nil {
+			return fmt.Errorf("failed to apply changes: %w", err)
+		}
+		if id := backup.ID(); id != "" {
+			ui.Infof("修改前的文件已备份 (ID: %s)，可使用 'backup restore %s' 回滚。", id, id)
+		}
+		return finishRedeploy(cmd, rimeManager, configuredSchemasOrNone(rimeManager))
+	},
+}
+
+// configReport is the structured (--format json|yaml|csv) form of config show.
+type configReport struct {
+	ConfigPath string                `json:"config_path" yaml:"config_path"`
+	LogFile    string                `json:"log_file" yaml:"log_file"`
+	Config     *manager.LoggerConfig `json:"config" yaml:"config"`
+}
+
+// sortedConfigKeys returns the keys of m, those in known first and in that
+// order, then any others alphabetically.
+func sortedConfigKeys(m map[string]bool, known []string) []string {
+	var keys, extra []string
+	seen := make(map[string]bool, len(known))
+	for _, k := range known {
+		seen[k] = true
+		if _, ok := m[k]; ok {
+			keys = append(keys, k)
+		}
+	}
+	for k := range m {
+		if !seen[k] {
+			extra = append(extra, k)
+		}
+	}
+	sort.Strings(extra)
+	return append(keys, extra...)
+}
+
+func onOff(on bool) string {
+	if on {
+		return ui.SuccessTxt("是")
+	}
+	return "否"
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configShowCmd, configGetCmd, configSetCmd)
+
+	configSetCmd.Flags().String("preset", "", "要修改的预设 (默认为当前生效的预设)")
+	addDryRunFlag(configSetCmd)
+	addRedeployFlag(configSetCmd)
+}
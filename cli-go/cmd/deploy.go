@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"rime-wanxiang-logger-go/internal/manager"
+	"rime-wanxiang-logger-go/internal/ui"
+
+	"github.com/spf13/cobra"
+)
+
+// deployers returns the deployers deploy and --redeploy choose from. It is a
+// variable so that it can be swapped for a manager.FakeDeployer.
+var deployers = manager.Deployers
+
+// defaultDeployTimeout is how long to wait for build/ to be updated after
+// triggering a deployment; fcitx5 and ibus deploy in the background.
+const defaultDeployTimeout = 30 * time.Second
+
+// deployCmd represents the deploy command
+var deployCmd = &cobra.Command{
+	Use:   "deploy",
+	Short: "Redeploy Rime so that changes take effect.",
+	Long: `This command triggers a Rime deployment of the user directory, the same as
+choosing "Deploy" in the frontend's menu. The mechanism is picked from the
+frontend owning the directory:
+
+  fcitx5     fcitx5-remote -r
+  fcitx      fcitx-remote -r
+  ibus       ibus restart
+  otherwise  rime_deployer --build <user dir> [<shared data dir>]
+
+Use --deployer to choose one explicitly. Afterwards the user directory is
+re-checked until Rime records a new deployment and every schema containing the
+logger (or those given with --schema) matches its sources in build/, or
+--timeout expires.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ui.Section("重新部署 Rime")
+
+		name, _ := cmd.Flags().GetString("deployer")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+
+		rimeManager, err := newRimeManager()
+		if err != nil {
+			return fmt.Errorf("failed to initialize Rime manager: %w", err)
+		}
+		ui.Successf("找到 Rime 目录: %s", rimeManager.UserDirectory)
+
+		schemaIDs, err := configuredTargetSchemas(cmd, rimeManager)
+		if err != nil {
+			return err
+		}
+		return redeploy(rimeManager, name, schemaIDs, timeout)
+	},
+}
+
+// addRedeployFlag registers the --redeploy flag of the commands that change
+// what Rime loads.
+func addRedeployFlag(c *cobra.Command) {
+	c.Flags().Bool("redeploy", false, "完成后立即重新部署 Rime (同 'deploy' 命令)")
+}
+
+// finishRedeploy redeploys Rime if --redeploy was given and otherwise
+// reminds the user to, naming the schemas whose deployed copy in build/ no
+// longer matches their sources.
+func finishRedeploy(c *cobra.Command, rimeManager *manager.RimeManager, schemaIDs []string) error {
+	if ok, _ := c.Flags().GetBool("redeploy"); ok {
+		ui.Subsection("重新部署 Rime...")
+		return redeploy(rimeManager, "", schemaIDs, defaultDeployTimeout)
+	}
+
+	if pending := rimeManager.PendingRedeploy(schemaIDs); len(pending) > 0 {
+		ui.Warnf("重要提示: 部署后的输入方案 %s 与当前配置不一致，您必须立即 '重新部署' Rime才能使更改生效。", strings.Join(pending, ", "))
+	} else {
+		ui.Warnf("重要提示: 您必须立即 '重新部署' Rime才能使更改生效。")
+	}
+	ui.Infof("可运行 'deploy' 或在命令中加上 --redeploy 自动重新部署。")
+	return nil
+}
+
+// redeploy runs the deployer called name (or the one suited to the frontend)
+// and checks build/ for schemaIDs afterwards.
+func redeploy(rimeManager *manager.RimeManager, name string, schemaIDs []string, timeout time.Duration) error {
+	deployer, err := rimeManager.SelectDeployer(deployers(), name)
+	if errors.Is(err, manager.ErrNoDeployer) {
+		ui.Errorf("未找到可用的部署方式 (rime_deployer、fcitx5-remote、fcitx-remote 或 ibus)。请在输入法菜单中手动 '重新部署'。")
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	ui.Infof("使用 %s 重新部署...", deployer.Name())
+	pending, err := rimeManager.Redeploy(deployer, schemaIDs, timeout)
+	if errors.Is(err, manager.ErrDeployTimeout) {
+		if len(pending) > 0 {
+			ui.Errorf("%s 内未检测到部署完成，部署后的输入方案 %s 仍与当前配置不一致。", timeout, strings.Join(pending, ", "))
+			return fmt.Errorf("deployment not confirmed for schemas: %s", strings.Join(pending, ", "))
+		}
+		ui.Errorf("%s 内未检测到部署完成，Rime 没有记录新的部署。请检查前端是否正在运行，或在输入法菜单中手动 '重新部署'。", timeout)
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to redeploy Rime: %w", err)
+	}
+
+	if len(schemaIDs) == 0 {
+		ui.Successf("重新部署完成。")
+	} else {
+		ui.Successf("重新部署完成，部署后的输入方案 %s 与当前配置一致。", strings.Join(schemaIDs, ", "))
+	}
+	return nil
+}
+
+// configuredSchemasOrNone returns the schemas containing the logger, or none
+// if they cannot be determined; used to verify a redeploy.
+func configuredSchemasOrNone(rimeManager *manager.RimeManager) []string {
+	schemaIDs, _ := rimeManager.ConfiguredSchemas()
+	return schemaIDs
+}
+
+func init() {
+	rootCmd.AddCommand(deployCmd)
+	addSchemaFlag(deployCmd)
+
+	deployCmd.Flags().String("deployer", "", "部署方式: rime_deployer|fcitx5|fcitx|ibus (默认根据前端自动选择)")
+	deployCmd.Flags().Duration("timeout", defaultDeployTimeout, "等待部署完成的最长时间")
+}
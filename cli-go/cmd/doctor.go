@@ -0,0 +1,355 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"rime-wanxiang-logger-go/internal/analyzer"
+	"rime-wanxiang-logger-go/internal/manager"
+	"rime-wanxiang-logger-go/internal/ui"
+
+	"github.com/spf13/cobra"
+)
+
+// doctorCmd represents the doctor command
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose why no log is being written.",
+	Long: `This command walks through everything that has to be in place for the logger
+to write events, and prints a concrete fix for each problem it finds:
+
+  - the deployed schema in build/ lists the logger processor
+  - every module the logger requires (e.g. lib) is found in the lua directory
+  - the effective config has enabled and log_events.text_committed turned on
+  - the log file can be written
+  - the log has no recent error events
+  - the log has been written to since the last deployment
+
+Every schema containing the logger is checked unless --schema is given.
+The command exits with a non-zero status when any check fails.`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ui.Section("Rime 日志记录器诊断")
+
+		report := collectDiagnosis(cmd)
+		if ui.Structured() {
+			if err := ui.Emit(report); err != nil {
+				return err
+			}
+			return report.err()
+		}
+
+		problems := 0
+		for _, c := range report.Checks {
+			switch {
+			case c.OK:
+				ui.Successf("%s", c.message)
+				continue
+			case c.warning:
+				ui.Warnf("%s", c.message)
+			default:
+				ui.Errorf("%s", c.message)
+			}
+			problems++
+			if c.Fix != "" {
+				ui.Printf("    修复: %s\n", c.Fix)
+			}
+		}
+
+		if problems == 0 {
+			ui.Section("未发现问题。")
+		} else {
+			ui.Section(fmt.Sprintf("发现 %d 个问题，请按上述提示修复。", problems))
+		}
+		return report.err()
+	},
+}
+
+// doctorErrorWindow is how far back error events in the log count as recent.
+const doctorErrorWindow = 7 * 24 * time.Hour
+
+// Doctor check names, used as stable identifiers in structured output.
+const (
+	checkLuaModule     = "lua_module"
+	checkConfigEnabled = "config_enabled"
+	checkConfigCommits = "config_text_committed"
+	checkLogWritable   = "log_writable"
+	checkLogErrors     = "log_errors"
+	checkLogFresh      = "log_fresh"
+)
+
+// doctorCheck is the outcome of a single diagnosis, with the fix for a failure.
+type doctorCheck struct {
+	Name   string `json:"name" yaml:"name"`
+	OK     bool   `json:"ok" yaml:"ok"`
+	Schema string `json:"schema,omitempty" yaml:"schema,omitempty"`
+	Path   string `json:"path,omitempty" yaml:"path,omitempty"`
+	Detail string `json:"detail,omitempty" yaml:"detail,omitempty"`
+	Fix    string `json:"fix,omitempty" yaml:"fix,omitempty"`
+
+	message string // human-readable line for text output
+	warning bool   // render a failed check as a warning rather than an error
+}
+
+// doctorReport is the structured (--format json|yaml|csv) form of the doctor output.
+type doctorReport struct {
+	RimeUserDirectory string        `json:"rime_user_directory" yaml:"rime_user_directory"`
+	LastDeploy        *time.Time    `json:"last_deploy,omitempty" yaml:"last_deploy,omitempty"`
+	Checks            []doctorCheck `json:"checks" yaml:"checks"`
+}
+
+// Table implements ui.Tabular with one row per check.
+func (r doctorReport) Table() ([]string, [][]string) {
+	rows := make([][]string, 0, len(r.Checks))
+	for _, c := range r.Checks {
+		rows = append(rows, []string{c.Name, strconv.FormatBool(c.OK), c.Schema, c.Path, c.Detail, c.Fix})
+	}
+	return []string{"name", "ok", "schema", "path", "detail", "fix"}, rows
+}
+
+// err returns an error counting the failed checks, or nil if all passed.
+func (r doctorReport) err() error {
+	failed := 0
+	for _, c := range r.Checks {
+		if !c.OK {
+			failed++
+		}
+	}
+	if failed == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d of %d checks failed", failed, len(r.Checks))
+}
+
+// collectDiagnosis runs every doctor check without printing anything.
+func collectDiagnosis(cmd *cobra.Command) doctorReport {
+	var report doctorReport
+
+	rimeManager, err := newRimeManager()
+	if err != nil {
+		report.Checks = append(report.Checks, doctorCheck{
+			Name:    checkRimeDirectory,
+			Detail:  err.Error(),
+			Fix:     "使用 --rime-dir 或环境变量 " + manager.RimeDirEnv + " 指定 Rime 用户目录。",
+			message: "未找到 Rime 用户目录。",
+		})
+		return report
+	}
+	report.RimeUserDirectory = rimeManager.UserDirectory
+
+	report.Checks = append(report.Checks, doctorScriptChecks(rimeManager)...)
+	report.Checks = append(report.Checks, doctorBuildChecks(cmd, rimeManager)...)
+	report.Checks = append(report.Checks, doctorConfigChecks(rimeManager)...)
+
+	lastDeploy, err := rimeManager.LastDeployTime()
+	if err == nil && !lastDeploy.IsZero() {
+		report.LastDeploy = &lastDeploy
+	}
+	report.Checks = append(report.Checks, doctorLogChecks(rimeManager, lastDeploy)...)
+	return report
+}
+
+// doctorScriptChecks checks the logger script and every module it requires.
+func doctorScriptChecks(rimeManager *manager.RimeManager) []doctorCheck {
+	loggerPath := filepath.Join(rimeManager.GetLuaDirectory(), manager.LoggerLuaFile)
+	script, err := os.ReadFile(loggerPath)
+	if err != nil {
+		return []doctorCheck{{
+			Name:    checkLoggerScript,
+			Path:    loggerPath,
+			Detail:  err.Error(),
+			Fix:     "运行 'install' 安装日志记录器，或运行 'repair' 恢复脚本。",
+			message: "未找到脚本: " + loggerPath,
+		}}
+	}
+
+	checks := []doctorCheck{{Name: checkLoggerScript, OK: true, Path: loggerPath, message: "找到脚本: " + loggerPath}}
+	for _, module := range manager.LuaRequires(script) {
+		check := doctorCheck{Name: checkLuaModule, Detail: module}
+		if path, ok := rimeManager.ResolveLuaModule(module); ok {
+			check.OK = true
+			check.Path = path
+			check.message = "日志脚本依赖的 Lua 模块 '" + module + "' 可以加载: " + path
+		} else {
+			check.Path = filepath.Join(rimeManager.GetLuaDirectory(), module+".lua")
+			check.Fix = "日志脚本依赖万象方案自带的 lua/" + module + ".lua，请安装或更新万象方案的 lua 目录。"
+			check.message = "找不到日志脚本依赖的 Lua 模块 '" + module + "'，脚本加载时会出错。"
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// doctorBuildChecks checks that the deployed copy of each schema lists the logger.
+func doctorBuildChecks(cmd *cobra.Command, rimeManager *manager.RimeManager) []doctorCheck {
+	schemaIDs, err := configuredTargetSchemas(cmd, rimeManager)
+	if err != nil {
+		return []doctorCheck{{
+			Name:    checkBuildDeployed,
+			Detail:  err.Error(),
+			warning: true,
+			message: "无法确定要检查的输入方案。错误: " + err.Error(),
+		}}
+	}
+	if len(schemaIDs) == 0 {
+		return []doctorCheck{{
+			Name:    checkBuildDeployed,
+			Fix:     "运行 'install' 将日志记录器加入输入方案，然后重新部署 Rime。",
+			message: "没有任何输入方案配置了日志记录器。",
+		}}
+	}
+
+	var checks []doctorCheck
+	for _, schemaID := range schemaIDs {
+		check := doctorCheck{Name: checkBuildDeployed, Schema: schemaID, Path: rimeManager.GetBuildSchemaPath(schemaID)}
+		deployed, err := rimeManager.CheckBuildConfigured(schemaID)
+		switch {
+		case errors.Is(err, manager.ErrNotDeployed):
+			check.Detail = err.Error()
+			check.Fix = "重新部署 Rime。"
+			check.message = "输入方案 '" + schemaID + "' 尚未部署，build 目录中没有编译后的方案。"
+		case err != nil:
+			check.Detail = err.Error()
+			check.warning = true
+			check.message = "无法读取部署后的输入方案。错误: " + err.Error()
+		case deployed:
+			check.OK = true
+			check.message = "部署后的输入方案 '" + schemaID + "' 包含日志记录器。"
+		default:
+			check.Fix = "重新部署 Rime。如果部署后仍然缺失，请检查是否有其他补丁覆盖了 engine/processors。"
+			check.message = "部署后的输入方案 '" + schemaID + "' 不包含日志记录器，Rime 不会加载它。"
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// doctorConfigChecks checks that the effective config logs committed text.
+func doctorConfigChecks(rimeManager *manager.RimeManager) []doctorCheck {
+	config, err := rimeManager.LoadLoggerConfig()
+	if err != nil {
+		return []doctorCheck{{
+			Name:    checkConfigEnabled,
+			Path:    rimeManager.GetConfigPath(),
+			Detail:  err.Error(),
+			warning: true,
+			Fix:     "修正配置文件中的错误，或运行 'upgrade' 重新生成配置文件。",
+			message: "无法加载配置文件，记录器将使用默认配置。错误: " + err.Error(),
+		}}
+	}
+
+	enabled := doctorCheck{Name: checkConfigEnabled, OK: config.Enabled, Path: rimeManager.GetConfigPath()}
+	if config.Enabled {
+		enabled.message = "预设 '" + config.Preset + "' 已启用日志记录。"
+	} else {
+		enabled.Fix = "运行 'config set enabled true'，然后重新部署 Rime。"
+		enabled.message = "预设 '" + config.Preset + "' 关闭了日志记录 (enabled = false)。"
+	}
+
+	commits := doctorCheck{Name: checkConfigCommits, OK: config.LogEvents["text_committed"], Path: rimeManager.GetConfigPath()}
+	if commits.OK {
+		commits.message = "预设 '" + config.Preset + "' 会记录上屏事件 (text_committed)。"
+	} else {
+		commits.Fix = "运行 'config set log_events.text_committed true'，然后重新部署 Rime。"
+		commits.message = "预设 '" + config.Preset + "' 不记录上屏事件 (text_committed)，分析命令将没有数据。"
+	}
+	return []doctorCheck{enabled, commits}
+}
+
+// doctorLogChecks checks that the log can be written, has no recent errors
+// and has been written to since lastDeploy.
+func doctorLogChecks(rimeManager *manager.RimeManager, lastDeploy time.Time) []doctorCheck {
+	logFilePath, err := rimeManager.GetLogFilePath()
+	if err != nil {
+		return []doctorCheck{{
+			Name:    checkLogFile,
+			Detail:  err.Error(),
+			warning: true,
+			message: "无法确定日志文件路径。错误: " + err.Error(),
+		}}
+	}
+
+	writable := doctorCheck{Name: checkLogWritable, Path: logFilePath}
+	if err := manager.CheckWritable(logFilePath); err != nil {
+		writable.Detail = err.Error()
+		writable.Fix = "检查 '" + filepath.Dir(logFilePath) + "' 的权限，或运行 'config set log_file_path <可写路径>' 更换日志位置。"
+		writable.message = "日志文件不可写: " + err.Error()
+	} else {
+		writable.OK = true
+		writable.message = "日志文件可写: " + logFilePath
+	}
+	checks := []doctorCheck{writable}
+
+	// A rotated log is read together with its archives
+	logFiles, err := rimeManager.LogFiles()
+	if err == nil && len(logFiles) == 0 {
+		check := doctorCheck{Name: checkLogFresh, Path: logFilePath, Fix: "确认以上各项无误并重新部署 Rime 后，打几个字再运行 'doctor'。"}
+		check.message = "尚未生成日志文件: " + logFilePath
+		return append(checks, check)
+	}
+
+	var lastEvent time.Time
+	var recentErrors []*analyzer.ErrorEvent
+	cutoff := time.Now().Add(-doctorErrorWindow)
+	if err == nil {
+		err = analyzer.ScanLogs(logFiles, analyzer.ScanOptions{OnInvalidLine: func(int, error) {}}, func(e analyzer.Event) error {
+			t := e.Time()
+			if t.After(lastEvent) {
+				lastEvent = t
+			}
+			if errEvent, ok := e.(*analyzer.ErrorEvent); ok && t.After(cutoff) {
+				recentErrors = append(recentErrors, errEvent)
+			}
+			return nil
+		})
+	}
+	if err != nil {
+		return append(checks, doctorCheck{
+			Name:    checkLogFile,
+			Path:    logFilePath,
+			Detail:  err.Error(),
+			warning: true,
+			message: "无法读取日志文件。错误: " + err.Error(),
+		})
+	}
+
+	errorsCheck := doctorCheck{Name: checkLogErrors, OK: len(recentErrors) == 0, Path: logFilePath}
+	if errorsCheck.OK {
+		errorsCheck.message = "最近 7 天的日志中没有错误事件。"
+	} else {
+		latest := recentErrors[len(recentErrors)-1]
+		errorsCheck.Detail = latest.Component + ": " + latest.Message
+		errorsCheck.Fix = "根据错误信息修正配置；如果错误来自脚本本身，可运行 'repair' 恢复原始脚本。"
+		errorsCheck.message = fmt.Sprintf("最近 7 天的日志中有 %d 个错误事件，最近一个: [%s] %s",
+			len(recentErrors), latest.Component, latest.Message)
+	}
+	checks = append(checks, errorsCheck)
+
+	fresh := doctorCheck{Name: checkLogFresh, Path: logFilePath}
+	switch {
+	case lastEvent.IsZero():
+		fresh.Fix = "确认以上各项无误并重新部署 Rime 后，打几个字再运行 'doctor'。"
+		fresh.message = "日志文件中没有任何事件。"
+	case lastDeploy.IsZero() || !lastEvent.Before(lastDeploy):
+		fresh.OK = true
+		fresh.Detail = lastEvent.Format(time.RFC3339)
+		fresh.message = "最后一条日志记录于 " + lastEvent.Format("2006-01-02 15:04:05") + "，晚于最近一次部署。"
+	default:
+		fresh.Detail = lastEvent.Format(time.RFC3339)
+		fresh.warning = true
+		fresh.Fix = "打几个字后再运行 'doctor'。如果仍然没有新记录，说明部署后的 Rime 没有加载日志记录器，请检查以上各项。"
+		fresh.message = "最近一次部署 (" + lastDeploy.Format("2006-01-02 15:04:05") + ") 之后没有新的日志，最后一条记录于 " +
+			lastEvent.Format("2006-01-02 15:04:05") + "。"
+	}
+	return append(checks, fresh)
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+	addSchemaFlag(doctorCmd)
+}
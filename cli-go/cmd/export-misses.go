@@ -2,10 +2,8 @@ package cmd
 
 import (
 	"fmt"
-	"os"
 
 	"rime-wanxiang-logger-go/internal/analyzer"
-	"rime-wanxiang-logger-go/internal/manager"
 	"rime-wanxiang-logger-go/internal/ui"
 
 	"github.com/spf13/cobra"
@@ -16,49 +14,70 @@ var exportMissesCmd = &cobra.Command{
 	Short: "Export misprediction report to a CSV file.",
 	Long: `This command processes the log data and filters for entries where the
 selected candidate was not the first one predicted by Rime. It then generates
-a CSV report of these mispredictions.`,
+a CSV report of these mispredictions. With --aggregate, identical
+(input, prediction, choice) pairs are merged into a single row with counts.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ui.Section("导出预测错误报告")
 
 		// Initialize RimeManager to get proper log file path
-		rimeManager, err := manager.NewRimeManager()
+		rimeManager, err := newRimeManager()
 		if err != nil {
 			return fmt.Errorf("could not initialize Rime manager: %w", err)
 		}
 
 		// Get the actual log file path (parses Lua config)
-		logFilePath, err := rimeManager.GetLogFilePath()
+		logFilePath, logFiles, err := logSources(cmd, rimeManager)
 		if err != nil {
 			return fmt.Errorf("failed to determine log file path: %w", err)
 		}
 
 		// Check if log file exists
-		if _, err := os.Stat(logFilePath); os.IsNotExist(err) {
+		if len(logFiles) == 0 {
 			return fmt.Errorf("❌ 未找到日志文件: %s\n请确保记录器已安装并生成了数据", logFilePath)
 		}
 
 		// Get output path from flags or use default
 		outFilePath, _ := cmd.Flags().GetString("output")
 
-		ui.Infof("正在读取日志文件: %s", logFilePath)
+		scanOpts, err := logScanOptions(cmd)
+		if err != nil {
+			return err
+		}
+
+		ui.Infof("正在读取日志文件: %s", describeLogSources(logFilePath, logFiles))
 
-		// Read and parse the log file
-		events, err := analyzer.ReadLogFile(logFilePath)
+		// Stream the log file, keeping only the mispredictions
+		var collector missExporter = analyzer.NewMissCollector()
+		aggregate, _ := cmd.Flags().GetBool("aggregate")
+		if aggregate {
+			collector = analyzer.NewMissAggregator()
+		}
+		err = analyzer.ScanCommits(logFiles, scanOpts, func(event *analyzer.TextCommittedEvent) error {
+			collector.Add(event)
+			return nil
+		})
 		if err != nil {
 			return fmt.Errorf("failed to read log file: %w", err)
 		}
 
-		if len(events) == 0 {
+		if collector.TotalCommits() == 0 {
 			ui.Warnf("日志文件中未找到 'text_committed' 事件。")
 			return nil
 		}
 
-		// Count mispredictions first
-		var missCount int
-		for _, event := range events {
-			if event.SelectedCandidateRank != nil && *event.SelectedCandidateRank > 0 {
-				missCount++
+		missCount := collector.MissCount()
+		if ui.Structured() {
+			if missCount > 0 {
+				if err := collector.WriteCSV(outFilePath); err != nil {
+					return fmt.Errorf("failed to export mispredictions: %w", err)
+				}
 			}
+			return ui.Emit(exportMissesReport{
+				LogFile:      logFilePath,
+				OutputFile:   outFilePath,
+				TotalCommits: collector.TotalCommits(),
+				MissCount:    missCount,
+			})
 		}
 
 		if missCount == 0 {
@@ -66,15 +85,19 @@ a CSV report of these mispredictions.`,
 			return nil
 		}
 
-		ui.Infof("在 %d 次总提交中发现 %d 次预测错误", len(events), missCount)
+		ui.Infof("在 %d 次总提交中发现 %d 次预测错误", collector.TotalCommits(), missCount)
 		ui.Infof("正在导出到: %s", outFilePath)
 
 		// Export mispredictions to CSV
-		if err := analyzer.ExportMisses(events, outFilePath); err != nil {
+		if err := collector.WriteCSV(outFilePath); err != nil {
 			return fmt.Errorf("failed to export mispredictions: %w", err)
 		}
 
-		ui.Successf("成功导出 %d 条预测错误记录到 '%s'", missCount, outFilePath)
+		if aggregator, ok := collector.(*analyzer.MissAggregator); ok {
+			ui.Successf("成功将 %d 条预测错误汇总为 %d 个组合并导出到 '%s'", missCount, aggregator.PairCount(), outFilePath)
+		} else {
+			ui.Successf("成功导出 %d 条预测错误记录到 '%s'", missCount, outFilePath)
+		}
 		ui.Infof("您可以打开此 CSV 文件来查看具体的预测失误案例。")
 		ui.Infof("最常见的错误会显示在文件顶部。")
 
@@ -82,9 +105,27 @@ a CSV report of these mispredictions.`,
 	},
 }
 
+// missExporter is implemented by both the per-miss and the aggregated CSV reports.
+type missExporter interface {
+	Add(event *analyzer.TextCommittedEvent)
+	TotalCommits() int
+	MissCount() int
+	WriteCSV(outputCsvPath string) error
+}
+
+// exportMissesReport is the structured (--format json|yaml|csv) summary of an export.
+type exportMissesReport struct {
+	LogFile      string `json:"log_file" yaml:"log_file"`
+	OutputFile   string `json:"output_file" yaml:"output_file"`
+	TotalCommits int    `json:"total_commits" yaml:"total_commits"`
+	MissCount    int    `json:"miss_count" yaml:"miss_count"`
+}
+
 func init() {
 	rootCmd.AddCommand(exportMissesCmd)
 
 	// Add flags for customizing output path
 	exportMissesCmd.Flags().StringP("output", "o", "mispredictions.csv", "输出 CSV 文件的路径")
+	exportMissesCmd.Flags().Bool("aggregate", false, "按 (用户输入, 程序预测, 实际选择) 汇总，输出次数、平均排名和首末出现时间")
+	addLogReadFlags(exportMissesCmd)
 }
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -21,12 +22,22 @@ var installCmd = &cobra.Command{
 	Short: "Install the logger scripts into the Rime user directory.",
 	Long: `This command detects the Rime user directory, copies the necessary Lua scripts
 (input_habit_logger.lua and input_habit_logger_config.lua) into it,
-and modifies the Rime schema to enable the logger with interactive preset selection.`,
+and modifies the Rime schema to enable the logger with interactive preset selection.
+Pass --preset (or --yes) to install unattended, e.g. from a provisioning script.
+By default the wanxiang schema is modified; use --schema to target other
+schemas (comma separated, or 'all' for every schema in schema_list).
+The processor is inserted after the punctuator unless --position says otherwise.
+With --mode patch the schema file is left alone and the logger is added
+through <schema>.custom.yaml instead, which survives schema updates and also
+works for schemas deployed from the shared data directory. A patch always
+inserts the logger first, so --position is rejected in that mode.
+With --dry-run every planned change is printed as a unified diff and nothing
+is written.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ui.Section("开始安装日志记录器")
 
 		// 1. Initialize RimeManager
-		rimeManager, err := manager.NewRimeManager()
+		rimeManager, err := newRimeManager()
 		if err != nil {
 			ui.Errorf("错误: 未找到 Rime 用户目录，无法安装。")
 			return fmt.Errorf("failed to initialize Rime manager: %w", err)
@@ -34,168 +45,224 @@ and modifies the Rime schema to enable the logger with interactive preset select
 
 		ui.Successf("找到 Rime 目录: %s", rimeManager.UserDirectory)
 
-		// 2. Interactive preset selection (matching Python version)
-		presetMap := map[string]string{
-			"✅ 普通模式 (Normal) - 推荐，用于计算输入法预测准确率":      "normal",
-			"👩‍💻 词库贡献者模式 (Developer) - 用于调试，关注非首选上屏": "developer",
-			"🔬 高级模式 (Advanced) - 记录几乎所有信息，用于深度分析":    "advanced",
-			"⚙️ 自定义 (Custom) - (需要手动修改配置文件)":         "custom",
+		// 2. Preset selection: from --preset, or interactively (matching Python version)
+		selectedPreset, _ := cmd.Flags().GetString("preset")
+		assumeYes, _ := cmd.Flags().GetBool("yes")
+		keepConfig, _ := cmd.Flags().GetBool("keep-config")
+		mode, _ := cmd.Flags().GetString("mode")
+		if mode != manager.ModeSchema && mode != manager.ModePatch {
+			return fmt.Errorf("unknown mode %q (expected %s or %s)", mode, manager.ModeSchema, manager.ModePatch)
 		}
-
-		var presetChoices []string
-		for key := range presetMap {
-			presetChoices = append(presetChoices, key)
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		position, _ := cmd.Flags().GetString("position")
+		pos, err := manager.ParseProcessorPosition(position)
+		if err != nil {
+			return err
 		}
-
-		prompt := promptui.Select{
-			Label: "请选择一个日志记录预设模式",
-			Items: presetChoices,
+		// A patch entry can only put the logger first ("engine/processors/@before 0")
+		if mode == manager.ModePatch && cmd.Flags().Changed("position") && pos != (manager.ProcessorPosition{Before: true}) {
+			return fmt.Errorf("--position %s cannot be used with --mode patch, which always inserts the logger first", position)
 		}
 
-		_, selectedChoice, err := prompt.Run()
-		if err != nil {
-			fmt.Println("Installation cancelled.")
-			return nil
+		switch {
+		case selectedPreset != "":
+			if !manager.IsValidPreset(selectedPreset) {
+				return fmt.Errorf("unknown preset %q (expected one of: %s)", selectedPreset, strings.Join(manager.Presets, ", "))
+			}
+		case assumeYes:
+			selectedPreset = "normal"
+		case !isInteractive():
+			return errNotInteractive
+		default:
+			preset, ok, err := promptPreset()
+			if err != nil {
+				return err
+			}
+			if !ok {
+				ui.Infof("Installation cancelled.")
+				return nil
+			}
+			selectedPreset = preset
 		}
 
-		selectedPreset := presetMap[selectedChoice]
+		ui.Infof("已选择预设: %s", selectedPreset)
 
-		// Handle custom preset selection
-		if selectedPreset == "custom" {
-			ui.Infof("您选择了 '自定义' 模式。")
-			ui.Infof("请先使用其他模式安装，然后手动编辑以下文件以符合您的需求:")
-			ui.Infof("%s", filepath.Join(rimeManager.GetLuaDirectory(), "input_habit_logger_config.lua"))
-			return nil
+		schemaIDs, err := installTargetSchemas(cmd, rimeManager, assumeYes)
+		if err != nil {
+			return err
 		}
+		ui.Infof("目标输入方案: %s", strings.Join(schemaIDs, ", "))
 
-		ui.Infof("已选择预设: %s", selectedPreset)
+		// Every change is planned first, so --dry-run shows exactly what a real run does
+		plan := manager.NewPlan()
 
 		// 3. 步骤 1: 复制 Lua 脚本
-		ui.Subsection("步骤 1: 复制 Lua 脚本...")
+		ui.Subsection("步骤 1: 准备 Lua 脚本...")
 
 		luaDir := rimeManager.GetLuaDirectory()
-		if err := os.MkdirAll(luaDir, 0755); err != nil {
-			return fmt.Errorf("failed to create lua directory at %s: %w", luaDir, err)
-		}
-
-		// Copy logger script
-		loggerScriptPath := filepath.Join(luaDir, "input_habit_logger.lua")
-		if err := os.WriteFile(loggerScriptPath, assets.LoggerScript, 0644); err != nil {
-			return fmt.Errorf("failed to write logger script: %w", err)
+		loggerScriptPath := filepath.Join(luaDir, manager.LoggerLuaFile)
+		if err := plan.Write(loggerScriptPath, assets.LoggerScript); err != nil {
+			return err
 		}
-		ui.Successf("已安装: %s", loggerScriptPath)
 
 		// Copy and modify config script with selected preset
-		configScriptPath := filepath.Join(luaDir, "input_habit_logger_config.lua")
+		configScriptPath := filepath.Join(luaDir, manager.ConfigLuaFile)
 
-		// Read the original config content
-		configContent := string(assets.ConfigScript)
+		if _, err := os.Stat(configScriptPath); err == nil && keepConfig {
+			ui.Infof("已保留现有配置文件: %s", configScriptPath)
+		} else {
+			// Read the original config content
+			configContent := string(assets.ConfigScript)
 
-		// Replace the preset choice (matching Python logic)
-		presetRegex := regexp.MustCompile(`local\s+preset_choice\s*=\s*".*"`)
-		newConfigContent := presetRegex.ReplaceAllString(configContent, fmt.Sprintf(`local preset_choice = "%s"`, selectedPreset))
+			// Replace the preset choice (matching Python logic)
+			presetRegex := regexp.MustCompile(`local\s+preset_choice\s*=\s*".*"`)
+			newConfigContent := presetRegex.ReplaceAllString(configContent, fmt.Sprintf(`local preset_choice = "%s"`, selectedPreset))
 
-		if err := os.WriteFile(configScriptPath, []byte(newConfigContent), 0644); err != nil {
-			return fmt.Errorf("failed to write config script: %w", err)
+			if err := plan.Write(configScriptPath, []byte(newConfigContent)); err != nil {
+				return err
+			}
+			ui.Infof("配置文件将使用预设 '%s': %s", selectedPreset, configScriptPath)
 		}
-		ui.Successf("已安装配置文件，预设为 '%s': %s", selectedPreset, configScriptPath)
 
 		// 4. 步骤 2: 修改输入方案文件
-		ui.Subsection("步骤 2: 修改输入方案文件...")
-		if err := modifySchemaForInstall(rimeManager); err != nil {
-			return fmt.Errorf("failed to modify schema file: %w", err)
+		if mode == manager.ModePatch {
+			ui.Subsection("步骤 2: 准备输入方案补丁...")
+			for _, schemaID := range schemaIDs {
+				if err := planPatchInstall(rimeManager, plan, schemaID, pos); err != nil {
+					return fmt.Errorf("failed to patch schema: %w", err)
+				}
+			}
+		} else {
+			ui.Subsection("步骤 2: 准备修改输入方案文件...")
+			for _, schemaID := range schemaIDs {
+				if configured, err := rimeManager.CheckPatchConfigured(schemaID); err == nil && configured {
+					ui.Infof("输入方案 '%s' 已通过 '%s' 配置，无需更改。", schemaID, manager.CustomFileName(schemaID))
+					continue
+				}
+				if err := planSchemaInstall(rimeManager, plan, schemaID, pos); err != nil {
+					return fmt.Errorf("failed to modify schema file: %w", err)
+				}
+			}
 		}
 
-		// 5. Installation complete
-		ui.Section("安装成功！")
-		ui.Warnf("重要提示: 您必须立即 '重新部署' Rime才能使更改生效。")
+		if dryRun {
+			return showPlan(plan)
+		}
 
-		return nil
+		// 5. 步骤 3: 写入更改 (修改前的文件会先备份)
+		ui.Subsection("步骤 3: 写入更改...")
+		backup, err := applyPlan(rimeManager, plan, "install")
+		if err != nil {
+			return fmt.Errorf("failed to apply changes: %w", err)
+		}
+
+		// 6. Installation complete
+		ui.Section("安装成功！")
+		if id := backup.ID(); id != "" {
+			ui.Infof("修改前的文件已备份 (ID: %s)，可使用 'backup restore %s' 回滚。", id, id)
+		}
+		if selectedPreset == "custom" {
+			ui.Infof("您选择了 '自定义' 模式，请编辑以下文件中的 presets.custom (或使用 'config set') 以符合您的需求:")
+			ui.Infof("%s", configScriptPath)
+		}
+		return finishRedeploy(cmd, rimeManager, schemaIDs)
 	},
 }
 
-// modifySchemaForInstall handles the schema file modification (matching Python _modify_schema_for_install)
-func modifySchemaForInstall(rimeManager *manager.RimeManager) error {
-	schemaPath := rimeManager.GetSchemaPath()
-
-	// Check if schema file exists
-	if _, err := os.Stat(schemaPath); os.IsNotExist(err) {
-		ui.Errorf("错误: 未找到 '%s'。", schemaPath)
-		ui.Warnf("请确保已安装 '万象' 输入方案并已至少部署过一次。")
-		return fmt.Errorf("schema file not found: %s", schemaPath)
-	}
+// presetOptions are the interactive menu entries, in display order.
+var presetOptions = []struct {
+	label  string
+	preset string
+}{
+	{"✅ 普通模式 (Normal) - 推荐，用于计算输入法预测准确率", "normal"},
+	{"👩‍💻 词库贡献者模式 (Developer) - 用于调试，关注非首选上屏", "developer"},
+	{"🔬 高级模式 (Advanced) - 记录几乎所有信息，用于深度分析", "advanced"},
+	{"⚙️ 自定义 (Custom) - (需要手动修改配置文件)", "custom"},
+}
 
-	// Read the schema file
-	content, err := os.ReadFile(schemaPath)
-	if err != nil {
-		ui.Errorf("发生意外错误: %v", err)
-		return err
+// promptPreset asks the user to choose a preset. ok is false if the prompt was cancelled.
+func promptPreset() (preset string, ok bool, err error) {
+	labels := make([]string, len(presetOptions))
+	for i, o := range presetOptions {
+		labels[i] = o.label
 	}
 
-	lines := strings.Split(string(content), "\n")
-	lineToAdd := strings.TrimSpace(manager.SchemaLineToAdd)
-
-	// Check if already configured
-	for _, line := range lines {
-		if strings.Contains(line, lineToAdd) {
-			ui.Infof("输入方案已配置，无需更改。")
-			return nil
-		}
+	prompt := promptui.Select{
+		Label: "请选择一个日志记录预设模式",
+		Items: labels,
 	}
 
-	// Find punctuator line (matching Python logic)
-	punctuatorIndex := -1
-	for i, line := range lines {
-		if strings.Contains(line, "punctuator") && strings.TrimSpace(line)[0] == '-' {
-			punctuatorIndex = i
-			break
+	index, _, err := prompt.Run()
+	if err != nil {
+		if errors.Is(err, promptui.ErrInterrupt) || errors.Is(err, promptui.ErrEOF) {
+			return "", false, nil
 		}
+		return "", false, err
 	}
+	return presetOptions[index].preset, true, nil
+}
 
-	if punctuatorIndex == -1 {
-		ui.Errorf("错误: 在输入方案中未找到 'punctuator' 入口。")
-		return fmt.Errorf("could not find 'punctuator' entry in schema")
-	}
+// planSchemaInstall plans adding the logger to the schema file's
+// engine/processors list (matching Python _modify_schema_for_install)
+func planSchemaInstall(rimeManager *manager.RimeManager, plan *manager.Plan, schemaID string, pos manager.ProcessorPosition) error {
+	schemaPath := rimeManager.GetSchemaPath(schemaID)
 
-	// Get indentation from punctuator line
-	punctuatorLine := lines[punctuatorIndex]
-	indentation := ""
-	for _, char := range punctuatorLine {
-		if char == ' ' || char == '\t' {
-			indentation += string(char)
-		} else {
-			break
+	// Check if schema file exists
+	if _, err := os.Stat(schemaPath); os.IsNotExist(err) {
+		ui.Errorf("错误: 未找到 '%s'。", schemaPath)
+		ui.Warnf("请确保已安装 '%s' 输入方案并已至少部署过一次。", schemaID)
+		if rimeManager.SchemaExists(schemaID) {
+			ui.Infof("该方案来自共享数据目录，可使用 --mode patch 通过 '%s' 安装。", manager.CustomFileName(schemaID))
 		}
+		return fmt.Errorf("schema file not found: %s", schemaPath)
 	}
 
-	// Create backup (matching Python logic)
-	backupPath := schemaPath + ".bak"
-	if err := os.WriteFile(backupPath, content, 0644); err != nil {
-		ui.Errorf("发生意外错误: %v", err)
+	changed, err := rimeManager.PlanSchemaInstall(plan, schemaID, pos)
+	switch {
+	case errors.Is(err, manager.ErrProcessorsNotFound):
+		ui.Errorf("错误: 在输入方案中未找到 engine/processors 列表。")
+		return err
+	case err != nil:
+		ui.Errorf("无法修改输入方案: %v", err)
 		return err
+	case !changed:
+		ui.Infof("输入方案 '%s' 已配置，无需更改。", schemaID)
+	default:
+		ui.Infof("将在 '%s' 的 engine/processors 中加入日志记录器 (%s)。", manager.SchemaFileName(schemaID), pos)
 	}
-	ui.Infof("已将原始输入方案备份至: %s", backupPath)
-
-	// Insert the line with proper indentation
-	lineWithIndent := indentation + lineToAdd
+	return nil
+}
 
-	// Create new lines slice with the inserted line
-	newLines := make([]string, 0, len(lines)+1)
-	newLines = append(newLines, lines[:punctuatorIndex+1]...)
-	newLines = append(newLines, lineWithIndent)
-	newLines = append(newLines, lines[punctuatorIndex+1:]...)
+// planPatchInstall plans adding the logger to <schema>.custom.yaml, unless the
+// schema file already loads it.
+func planPatchInstall(rimeManager *manager.RimeManager, plan *manager.Plan, schemaID string, pos manager.ProcessorPosition) error {
+	if configured, err := rimeManager.CheckSchemaConfigured(schemaID); err == nil && configured {
+		ui.Infof("输入方案文件 '%s' 已直接配置日志记录器，跳过补丁。", manager.SchemaFileName(schemaID))
+		return nil
+	}
 
-	// Write the modified content back
-	newContent := strings.Join(newLines, "\n")
-	if err := os.WriteFile(schemaPath, []byte(newContent), 0644); err != nil {
-		ui.Errorf("发生意外错误: %v", err)
+	changed, err := rimeManager.PlanPatchInstall(plan, schemaID, pos)
+	switch {
+	case err != nil:
+		ui.Errorf("无法写入补丁文件: %v", err)
 		return err
+	case !changed:
+		ui.Infof("补丁 '%s' 已配置，无需更改。", manager.CustomFileName(schemaID))
+	default:
+		ui.Infof("将在补丁 '%s' 中加入日志记录器。", manager.CustomFileName(schemaID))
 	}
-
-	ui.Successf("已成功配置 '%s'。", manager.SchemaYamlFile)
 	return nil
 }
 
 func init() {
 	rootCmd.AddCommand(installCmd)
+
+	installCmd.Flags().String("preset", "", "不经交互直接使用的预设: normal|developer|advanced|custom")
+	installCmd.Flags().BoolP("yes", "y", false, "不进行任何询问 (未指定 --preset 时使用 normal)")
+	installCmd.Flags().Bool("keep-config", false, "保留已存在的 input_habit_logger_config.lua，不覆盖")
+	installCmd.Flags().String("position", manager.DefaultProcessorPosition.String(), "日志处理器在 engine/processors 中的位置: first|last|after:<处理器>|before:<处理器>")
+	installCmd.Flags().String("mode", manager.ModeSchema, "安装方式: schema (直接修改方案文件) 或 patch (写入 <方案>.custom.yaml 补丁)")
+	addSchemaFlag(installCmd)
+	addDryRunFlag(installCmd)
+	addRedeployFlag(installCmd)
 }
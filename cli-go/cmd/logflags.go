@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"rime-wanxiang-logger-go/internal/analyzer"
+	"rime-wanxiang-logger-go/internal/manager"
+	"rime-wanxiang-logger-go/internal/ui"
+
+	"github.com/spf13/cobra"
+)
+
+// addLogReadFlags registers the flags shared by every command that reads the JSONL log:
+// the line size limit and the analyzer.Filter fields.
+func addLogReadFlags(c *cobra.Command) {
+	c.Flags().StringSlice("log", nil, "读取指定的日志文件 (可多次指定或用逗号分隔，支持 .gz 归档与 'merge' 的输出)，而非 Rime 用户目录中的日志与归档")
+	c.Flags().Int("max-line-size", analyzer.DefaultMaxLineSize, "单行日志的最大字节数 (启用候选词列表时日志行可能很长)")
+	c.Flags().String("since", "", "仅分析此时间之后的记录 (如 2024-05-01、\"2024-05-01 08:00\" 或 7d)")
+	c.Flags().String("until", "", "仅分析此时间之前的记录 (格式同 --since，单独日期包含当天)")
+	c.Flags().String("schema", "", "仅分析指定输入方案 (schema_id) 会话中的记录")
+	c.Flags().String("selection-method", "", "仅分析指定选择方式的上屏 (前缀匹配，如 nth_choice_number)")
+	c.Flags().Int("min-rank", 0, "仅分析选择排名不小于该值的上屏")
+	c.Flags().String("input-prefix", "", "仅分析输入编码以该前缀开头的上屏")
+}
+
+// logScanOptions builds analyzer.ScanOptions from the flags registered by addLogReadFlags.
+func logScanOptions(c *cobra.Command) (analyzer.ScanOptions, error) {
+	maxLineSize, _ := c.Flags().GetInt("max-line-size")
+	filter, err := logFilter(c)
+	if err != nil {
+		return analyzer.ScanOptions{}, err
+	}
+	return analyzer.ScanOptions{
+		MaxLineSize: maxLineSize,
+		Filter:      filter,
+		OnInvalidLine: func(lineNumber int, err error) {
+			ui.Warnf("跳过第 %d 行无效的 JSON: %v", lineNumber, err)
+		},
+	}, nil
+}
+
+// logSources returns the live log file and every file the analysis commands
+// read: the rotated archives in logs/, oldest first, then the live log. With
+// --log only the given files are read.
+func logSources(c *cobra.Command, rimeManager *manager.RimeManager) (string, []string, error) {
+	if files, _ := c.Flags().GetStringSlice("log"); len(files) > 0 {
+		for _, f := range files {
+			if _, err := os.Stat(f); err != nil {
+				return "", nil, err
+			}
+		}
+		return strings.Join(files, ", "), files, nil
+	}
+
+	logFilePath, err := rimeManager.GetLogFilePath()
+	if err != nil {
+		return "", nil, err
+	}
+	logFiles, err := rimeManager.LogFiles()
+	if err != nil {
+		return "", nil, err
+	}
+	return logFilePath, logFiles, nil
+}
+
+// describeLogSources names the live log and how many archives are read with
+// it, or the files given with --log.
+func describeLogSources(logFilePath string, logFiles []string) string {
+	if logFilePath == strings.Join(logFiles, ", ") {
+		return logFilePath
+	}
+	archives := 0
+	for _, f := range logFiles {
+		if f != logFilePath {
+			archives++
+		}
+	}
+	if archives == 0 {
+		return logFilePath
+	}
+	return fmt.Sprintf("%s (另含 %d 个归档)", logFilePath, archives)
+}
+
+// logFilter builds the analyzer.Filter described by the shared filter flags.
+func logFilter(c *cobra.Command) (analyzer.Filter, error) {
+	var filter analyzer.Filter
+	now := time.Now()
+
+	since, _ := c.Flags().GetString("since")
+	t, err := analyzer.ParseTimeBound(since, now, false)
+	if err != nil {
+		return filter, fmt.Errorf("invalid --since: %w", err)
+	}
+	filter.Since = t
+
+	until, _ := c.Flags().GetString("until")
+	t, err = analyzer.ParseTimeBound(until, now, true)
+	if err != nil {
+		return filter, fmt.Errorf("invalid --until: %w", err)
+	}
+	filter.Until = t
+
+	filter.SchemaID, _ = c.Flags().GetString("schema")
+	filter.SelectionMethod, _ = c.Flags().GetString("selection-method")
+	filter.InputPrefix, _ = c.Flags().GetString("input-prefix")
+	if c.Flags().Changed("min-rank") {
+		minRank, _ := c.Flags().GetInt("min-rank")
+		filter.MinRank = &minRank
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"rime-wanxiang-logger-go/internal/analyzer"
+	"rime-wanxiang-logger-go/internal/manager"
+	"rime-wanxiang-logger-go/internal/ui"
+
+	"github.com/spf13/cobra"
+)
+
+// mergeCmd represents the merge command
+var mergeCmd = &cobra.Command{
+	Use:   "merge [<device>=]<path>...",
+	Short: "Combine the logs of several machines into one time-ordered log.",
+	Long: `This command combines JSONL logs, plain or gzip-compressed, into a single log
+ordered by timestamp, for example the logs of Rime user directories synced
+from several computers. Each path is a log file, a rotated archive or a
+Rime user directory, whose logs/ archives and default log are all read.
+
+Every entry is tagged with a "device_id" naming the machine it came from:
+the <device> given before '=', else the installation_id in the
+installation.yaml next to the log, else the file or directory name. Entries
+that already carry a device_id keep it, so merged logs can be merged again.
+Exact duplicates, e.g. when a sync tool copied the same log twice, are
+written only once.
+
+Analyze the result with 'analyze --log <output> --by device'.
+
+Examples:
+  merge ~/.config/rime laptop=/mnt/backup/laptop/input_habit_log_structured.jsonl
+  merge -o team.jsonl.gz a=a.jsonl b=b.jsonl.gz`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ui.Section("合并日志")
+
+		output, _ := cmd.Flags().GetString("output")
+		force, _ := cmd.Flags().GetBool("force")
+		maxLineSize, _ := cmd.Flags().GetInt("max-line-size")
+
+		var sources []analyzer.MergeSource
+		for _, arg := range args {
+			source, err := mergeSource(arg)
+			if err != nil {
+				return err
+			}
+			for _, path := range source.Paths {
+				if samePath(path, output) {
+					return fmt.Errorf("output %s is also an input", output)
+				}
+			}
+			sources = append(sources, source)
+		}
+
+		if !ui.Structured() {
+			for _, s := range sources {
+				ui.Infof("设备 %s: %s", s.Device, strings.Join(s.Paths, ", "))
+			}
+		}
+
+		flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+		if !force {
+			flags |= os.O_EXCL
+		}
+		file, err := os.OpenFile(output, flags, 0644)
+		if os.IsExist(err) {
+			return fmt.Errorf("output %s already exists (use --force to overwrite)", output)
+		}
+		if err != nil {
+			return fmt.Errorf("could not create output file: %w", err)
+		}
+		defer file.Close()
+
+		var w io.Writer = file
+		var gz *gzip.Writer
+		if strings.HasSuffix(output, ".gz") {
+			gz = gzip.NewWriter(file)
+			w = gz
+		}
+
+		stats, err := analyzer.MergeLogs(sources, w, analyzer.MergeOptions{
+			MaxLineSize: maxLineSize,
+			OnInvalidLine: func(path string, lineNumber int, err error) {
+				ui.Warnf("跳过 %s 第 %d 行无效的 JSON: %v", path, lineNumber, err)
+			},
+		})
+		if err == nil && gz != nil {
+			err = gz.Close()
+		}
+		if err == nil {
+			err = file.Close()
+		}
+		if err != nil {
+			return fmt.Errorf("failed to merge logs: %w", err)
+		}
+
+		if ui.Structured() {
+			return ui.Emit(mergeReport{Output: output, Sources: sources, Stats: stats})
+		}
+
+		ui.PrintKV([][2]string{
+			{"读取记录", strconv.Itoa(stats.Read)},
+			{"写入记录", strconv.Itoa(stats.Written)},
+			{"重复记录 (已丢弃)", strconv.Itoa(stats.Duplicates)},
+			{"无效行 (已跳过)", strconv.Itoa(stats.Invalid)},
+		})
+		ui.Section("合并完成！")
+		ui.Infof("合并后的日志: %s", output)
+		ui.Infof("可运行 'analyze --log %s --by device' 比较各设备的预测准确度。", output)
+		return nil
+	},
+}
+
+// mergeReport is the structured (--format json|yaml|csv) form of the merge output.
+type mergeReport struct {
+	Output  string                 `json:"output" yaml:"output"`
+	Sources []analyzer.MergeSource `json:"sources" yaml:"sources"`
+	Stats   analyzer.MergeStats    `json:"stats" yaml:"stats"`
+}
+
+// mergeSource resolves a "[<device>=]<path>" argument of merge.
+func mergeSource(arg string) (analyzer.MergeSource, error) {
+	var source analyzer.MergeSource
+	path := arg
+	if device, rest, ok := strings.Cut(arg, "="); ok && device != "" && !strings.ContainsAny(device, `/\`) {
+		source.Device, path = device, rest
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return source, err
+	}
+
+	if info.IsDir() {
+		source.Paths, err = manager.LogFilesIn(path)
+		if err != nil {
+			return source, err
+		}
+		if len(source.Paths) == 0 {
+			return source, fmt.Errorf("no log files found in %s", path)
+		}
+		if source.Device == "" {
+			source.Device = manager.InstallationID(path)
+		}
+		if source.Device == "" {
+			source.Device = filepath.Base(filepath.Clean(path))
+		}
+		return source, nil
+	}
+
+	source.Paths = []string{path}
+	if source.Device == "" {
+		// Archives live in logs/ below the user directory holding installation.yaml
+		dir := filepath.Dir(path)
+		if source.Device = manager.InstallationID(dir); source.Device == "" && filepath.Base(dir) == manager.LogsDirName {
+			source.Device = manager.InstallationID(filepath.Dir(dir))
+		}
+	}
+	if source.Device == "" {
+		name := filepath.Base(path)
+		source.Device = strings.TrimSuffix(strings.TrimSuffix(name, ".gz"), ".jsonl")
+	}
+	return source, nil
+}
+
+// samePath reports whether a and b name the same existing file.
+func samePath(a, b string) bool {
+	ai, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	bi, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(ai, bi)
+}
+
+func init() {
+	rootCmd.AddCommand(mergeCmd)
+
+	mergeCmd.Flags().StringP("output", "o", "input_habit_log_merged.jsonl", "合并后的日志文件 (以 .gz 结尾时压缩)")
+	mergeCmd.Flags().Bool("force", false, "覆盖已存在的输出文件")
+	mergeCmd.Flags().Int("max-line-size", analyzer.DefaultMaxLineSize, "单行日志的最大字节数 (启用候选词列表时日志行可能很长)")
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"strconv"
+
+	"rime-wanxiang-logger-go/internal/manager"
+	"rime-wanxiang-logger-go/internal/ui"
+
+	"github.com/spf13/cobra"
+)
+
+// maxNewFileDiffLines is the longest created or removed file whose full diff
+// is shown in text output; longer ones (the Lua scripts) are summarised.
+const maxNewFileDiffLines = 40
+
+// addDryRunFlag registers the --dry-run flag shared by install and uninstall.
+func addDryRunFlag(c *cobra.Command) {
+	c.Flags().Bool("dry-run", false, "只显示将要进行的更改 (统一 diff 格式)，不写入任何文件")
+}
+
+// planChange is one change of a planReport.
+type planChange struct {
+	Kind  manager.ChangeKind `json:"kind" yaml:"kind"`
+	Path  string             `json:"path" yaml:"path"`
+	Lines int                `json:"lines" yaml:"lines"`
+	Diff  string             `json:"diff" yaml:"diff"`
+}
+
+// planReport is the structured (--format json|yaml|csv) form of a --dry-run plan.
+type planReport struct {
+	DryRun  bool         `json:"dry_run" yaml:"dry_run"`
+	Changes []planChange `json:"changes" yaml:"changes"`
+}
+
+// Table implements ui.Tabular with one row per change. Diffs are left out.
+func (r planReport) Table() ([]string, [][]string) {
+	rows := make([][]string, 0, len(r.Changes))
+	for _, c := range r.Changes {
+		rows = append(rows, []string{string(c.Kind), c.Path, strconv.Itoa(c.Lines)})
+	}
+	return []string{"kind", "path", "lines"}, rows
+}
+
+// showPlan prints every planned change as a unified diff without applying it.
+func showPlan(plan *manager.Plan) error {
+	if ui.Structured() {
+		report := planReport{DryRun: true, Changes: []planChange{}}
+		for _, c := range plan.Changes() {
+			report.Changes = append(report.Changes, planChange{Kind: c.Kind, Path: c.Path, Lines: c.LineCount(), Diff: c.Diff()})
+		}
+		return ui.Emit(report)
+	}
+
+	ui.Subsection("预览更改 (--dry-run，不会写入任何文件)")
+	if plan.Empty() {
+		ui.Infof("没有需要进行的更改。")
+		return nil
+	}
+	for _, c := range plan.Changes() {
+		ui.Infof("%s: %s", changeLabel(c.Kind), c.Path)
+		if c.Kind != manager.ChangeModify && c.LineCount() > maxNewFileDiffLines {
+			ui.Infof("  (共 %d 行，省略 diff)", c.LineCount())
+			continue
+		}
+		ui.PrintDiff(c.Diff())
+	}
+	return nil
+}
+
+// applyPlan backs up and applies every planned change, reporting each file.
+// It returns the backup set holding the previous state of the files.
+func applyPlan(rimeManager *manager.RimeManager, plan *manager.Plan, reason string) (*manager.BackupSet, error) {
+	backup := rimeManager.NewBackupSet(reason)
+	if err := rimeManager.ApplyPlan(plan, backup); err != nil {
+		return backup, err
+	}
+	for _, c := range plan.Changes() {
+		ui.Successf("%s: %s", changeLabel(c.Kind), c.Path)
+	}
+	return backup, nil
+}
+
+func changeLabel(kind manager.ChangeKind) string {
+	switch kind {
+	case manager.ChangeCreate:
+		return "新建"
+	case manager.ChangeRemove:
+		return "移除"
+	default:
+		return "修改"
+	}
+}
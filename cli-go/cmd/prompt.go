@@ -0,0 +1,22 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+
+	"github.com/mattn/go-isatty"
+)
+
+// errNotInteractive is returned when a command would need to prompt but
+// stdin or stdout is not a terminal (e.g. in a provisioning script).
+var errNotInteractive = errors.New("refusing to prompt: not running in an interactive terminal (pass --yes or the command's other non-interactive flags)")
+
+// isInteractive reports whether both stdin and stdout are terminals, so that
+// promptui prompts can be shown and answered.
+func isInteractive() bool {
+	return isTerminal(os.Stdin.Fd()) && isTerminal(os.Stdout.Fd())
+}
+
+func isTerminal(fd uintptr) bool {
+	return isatty.IsTerminal(fd) || isatty.IsCygwinTerminal(fd)
+}
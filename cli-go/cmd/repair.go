@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+
+	"rime-wanxiang-logger-go/internal/assets"
+	"rime-wanxiang-logger-go/internal/manager"
+	"rime-wanxiang-logger-go/internal/ui"
+
+	"github.com/spf13/cobra"
+)
+
+// repairCmd represents the repair command
+var repairCmd = &cobra.Command{
+	Use:   "repair",
+	Short: "Restore the pristine logger script, keeping the config.",
+	Long: `This command overwrites input_habit_logger.lua with the script embedded in
+this tool, undoing local edits or a broken copy. It also reinstalls a missing
+script. input_habit_logger_config.lua and the schema files are left alone;
+use 'upgrade' to move to a newer version while migrating the config.
+With --dry-run the planned change is printed and nothing is written.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ui.Section("修复日志记录器脚本")
+
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		rimeManager, err := newRimeManager()
+		if err != nil {
+			return fmt.Errorf("failed to initialize Rime manager: %w", err)
+		}
+		ui.Successf("找到 Rime 目录: %s", rimeManager.UserDirectory)
+
+		integrity, err := rimeManager.CheckLoggerIntegrity(assets.LoggerScript)
+		if err != nil {
+			return err
+		}
+		ui.Infof("当前脚本状态: %s", integrity.State)
+
+		plan := manager.NewPlan()
+		if err := plan.Write(integrity.Path, assets.LoggerScript); err != nil {
+			return err
+		}
+
+		if dryRun {
+			return showPlan(plan)
+		}
+		if plan.Empty() {
+			ui.Section("脚本与内置版本一致，无需修复。")
+			return nil
+		}
+
+		backup, err := applyPlan(rimeManager, plan, "repair")
+		if err != nil {
+			return fmt.Errorf("failed to apply changes: %w", err)
+		}
+
+		ui.Section("修复完成！")
+		if id := backup.ID(); id != "" {
+			ui.Infof("修改前的脚本已备份 (ID: %s)，可使用 'backup restore %s' 回滚。", id, id)
+		}
+		return finishRedeploy(cmd, rimeManager, configuredSchemasOrNone(rimeManager))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(repairCmd)
+	addDryRunFlag(repairCmd)
+	addRedeployFlag(repairCmd)
+}
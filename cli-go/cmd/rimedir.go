@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"rime-wanxiang-logger-go/internal/manager"
+	"rime-wanxiang-logger-go/internal/ui"
+
+	"github.com/manifoldco/promptui"
+)
+
+// newRimeManager returns a RimeManager for the directory given with
+// --rime-dir, else $RIME_USER_DIR, else the detected frontend directory.
+// When several frontends have a directory, the user chooses one; without a
+// terminal the first is used with a warning.
+func newRimeManager() (*manager.RimeManager, error) {
+	if dir, _ := rootCmd.PersistentFlags().GetString("rime-dir"); dir != "" {
+		return manager.NewRimeManagerAt(dir)
+	}
+	if os.Getenv(manager.RimeDirEnv) != "" {
+		return manager.NewRimeManager()
+	}
+
+	frontends := manager.DetectFrontends()
+	live := 0
+	for _, f := range frontends {
+		if !f.Sync {
+			live++
+		}
+	}
+	if live < 2 {
+		return manager.NewRimeManager()
+	}
+
+	if !isInteractive() || ui.Structured() {
+		ui.Warnf("检测到多个 Rime 用户目录，使用 %s (%s)。可用 --rime-dir 或 %s 指定。", frontends[0].Directory, frontends[0].Name, manager.RimeDirEnv)
+		return manager.NewRimeManagerAt(frontends[0].Directory)
+	}
+
+	frontend, ok, err := promptFrontend(frontends)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("no Rime user directory selected")
+	}
+	return manager.NewRimeManagerAt(frontend.Directory)
+}
+
+// promptFrontend asks the user which frontend directory to use. ok is false
+// if the prompt was cancelled.
+func promptFrontend(frontends []manager.Frontend) (frontend manager.Frontend, ok bool, err error) {
+	labels := make([]string, len(frontends))
+	for i, f := range frontends {
+		labels[i] = fmt.Sprintf("%s: %s", f.Name, f.Directory)
+		if f.Sync {
+			labels[i] += " (同步文件夹)"
+		}
+	}
+
+	prompt := promptui.Select{
+		Label: fmt.Sprintf("检测到多个 Rime 用户目录，请选择 (可用 --rime-dir 或 %s 跳过)", manager.RimeDirEnv),
+		Items: labels,
+	}
+	index, _, err := prompt.Run()
+	if err != nil {
+		if errors.Is(err, promptui.ErrInterrupt) || errors.Is(err, promptui.ErrEOF) {
+			return manager.Frontend{}, false, nil
+		}
+		return manager.Frontend{}, false, err
+	}
+	return frontends[index], true, nil
+}
@@ -3,6 +3,9 @@ package cmd
 import (
 	"os"
 
+	"rime-wanxiang-logger-go/internal/manager"
+	"rime-wanxiang-logger-go/internal/ui"
+
 	"github.com/spf13/cobra"
 )
 
@@ -16,6 +19,15 @@ for the Rime input method engine.`,
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	// Run: func(cmd *cobra.Command, args []string) { },
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		value, _ := cmd.Flags().GetString("format")
+		format, err := ui.ParseFormat(value)
+		if err != nil {
+			return err
+		}
+		ui.SetFormat(format)
+		return nil
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -33,6 +45,8 @@ func init() {
 	// will be global for your application.
 
 	// rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.cli-go.yaml)")
+	rootCmd.PersistentFlags().String("rime-dir", "", "Rime 用户目录 (默认读取 $"+manager.RimeDirEnv+"，否则自动检测前端目录)")
+	rootCmd.PersistentFlags().String("format", string(ui.FormatText), "输出格式: text|json|yaml|csv (json/yaml/csv 适合脚本处理，不含颜色和图标)")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"rime-wanxiang-logger-go/internal/analyzer"
+	"rime-wanxiang-logger-go/internal/manager"
+	"rime-wanxiang-logger-go/internal/ui"
+
+	"github.com/spf13/cobra"
+)
+
+// rotateCmd represents the rotate command
+var rotateCmd = &cobra.Command{
+	Use:   "rotate",
+	Short: "Move the log into compressed archives under logs/.",
+	Long: `The logger appends to a single JSONL file forever. This command moves that file
+into a dated, gzip-compressed archive in the logs/ folder of the Rime user
+directory, e.g. logs/20240501-083000_input_habit_log_structured.jsonl.gz.
+The logger opens the log for every entry, so it simply starts a new file.
+
+The log is rotated once it reaches --max-size or its oldest entry is older
+than --max-age (0 disables either limit); --force rotates it regardless.
+Every analysis command reads the archives followed by the live log, so
+rotating never loses history.
+
+Unlike the commands that edit config files, rotate moves and compresses the
+log instead of rewriting its content, so there is no diff to show: --dry-run
+only reports whether the log would be rotated, and nothing is moved.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ui.Section("日志轮转")
+
+		force, _ := cmd.Flags().GetBool("force")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		policy, err := rotationPolicy(cmd)
+		if err != nil {
+			return err
+		}
+
+		rimeManager, err := newRimeManager()
+		if err != nil {
+			return fmt.Errorf("failed to initialize Rime manager: %w", err)
+		}
+		logFilePath, err := rimeManager.GetLogFilePath()
+		if err != nil {
+			return fmt.Errorf("failed to determine log file path: %w", err)
+		}
+
+		report := rotateReport{LogFile: logFilePath, DryRun: dryRun, Archives: []manager.LogArchive{}}
+		info, err := os.Stat(logFilePath)
+		switch {
+		case os.IsNotExist(err):
+		case err != nil:
+			return fmt.Errorf("could not access log file: %w", err)
+		default:
+			report.Size = info.Size()
+			if oldest := firstEventTime(logFilePath); !oldest.IsZero() {
+				report.Oldest = &oldest
+			}
+		}
+
+		now := time.Now()
+		switch {
+		case report.Size == 0:
+			report.Reason = "log file is empty or missing"
+		case force:
+			report.Rotate, report.Reason = true, "forced"
+		default:
+			var oldest time.Time
+			if report.Oldest != nil {
+				oldest = *report.Oldest
+			}
+			report.Rotate, report.Reason = policy.Due(report.Size, oldest, now)
+			if !report.Rotate {
+				report.Reason = "within size and age limits"
+			}
+		}
+
+		if report.Rotate && !dryRun {
+			report.Archives, err = rimeManager.RotateLog(now)
+			if err != nil {
+				return fmt.Errorf("failed to rotate log: %w", err)
+			}
+		}
+
+		if ui.Structured() {
+			return ui.Emit(report)
+		}
+
+		oldest := "-"
+		if report.Oldest != nil {
+			oldest = report.Oldest.Local().Format("2006-01-02 15:04:05")
+		}
+		ui.PrintKV([][2]string{
+			{"日志文件", logFilePath},
+			{"大小", formatBytes(report.Size)},
+			{"最早记录", oldest},
+			{"归档目录", rimeManager.GetLogsDirectory()},
+		})
+
+		switch {
+		case !report.Rotate:
+			ui.Infof("无需轮转 (%s)。可使用 --force 强制轮转。", report.Reason)
+		case dryRun:
+			ui.Infof("将轮转日志 (%s)，--dry-run 模式下不移动任何文件。", report.Reason)
+		default:
+			for _, a := range report.Archives {
+				ui.Successf("已归档: %s (%s → %s)", a.Path, formatBytes(a.Size), formatBytes(a.CompressedSize))
+			}
+			ui.Section("日志轮转完成！")
+		}
+		return nil
+	},
+}
+
+// rotateReport is the structured (--format json|yaml|csv) form of the rotate output.
+type rotateReport struct {
+	LogFile  string               `json:"log_file" yaml:"log_file"`
+	Size     int64                `json:"size" yaml:"size"`
+	Oldest   *time.Time           `json:"oldest,omitempty" yaml:"oldest,omitempty"`
+	Rotate   bool                 `json:"rotate" yaml:"rotate"`
+	Reason   string               `json:"reason" yaml:"reason"`
+	DryRun   bool                 `json:"dry_run" yaml:"dry_run"`
+	Archives []manager.LogArchive `json:"archives" yaml:"archives"`
+}
+
+// rotationPolicy builds the manager.RotationPolicy described by --max-size and --max-age.
+func rotationPolicy(c *cobra.Command) (manager.RotationPolicy, error) {
+	var policy manager.RotationPolicy
+
+	maxSize, _ := c.Flags().GetString("max-size")
+	size, err := parseSize(maxSize)
+	if err != nil {
+		return policy, fmt.Errorf("invalid --max-size: %w", err)
+	}
+	policy.MaxSize = size
+
+	maxAge, _ := c.Flags().GetString("max-age")
+	if maxAge = strings.TrimSpace(maxAge); maxAge != "" && maxAge != "0" {
+		age, err := analyzer.ParseAge(maxAge)
+		if err != nil {
+			return policy, fmt.Errorf("invalid --max-age: %w", err)
+		}
+		policy.MaxAge = age
+	}
+	return policy, nil
+}
+
+// parseSize parses a byte count with an optional K, M or G suffix (powers
+// of 1024, optionally followed by B), e.g. "512K" or "10MB".
+func parseSize(value string) (int64, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	if value == "" {
+		return 0, nil
+	}
+	value = strings.TrimSuffix(value, "B")
+	multiplier := int64(1)
+	if n := len(value); n > 0 {
+		switch value[n-1] {
+		case 'K':
+			multiplier = 1 << 10
+		case 'M':
+			multiplier = 1 << 20
+		case 'G':
+			multiplier = 1 << 30
+		}
+		if multiplier > 1 {
+			value = value[:n-1]
+		}
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q (expected e.g. 512K, 10MB or 1G)", value)
+	}
+	return n * multiplier, nil
+}
+
+// formatBytes renders a byte count with a binary unit, e.g. "1.5 MB".
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
+
+// firstEventTime returns the time of the first timestamped entry of a log file.
+func firstEventTime(logFilePath string) time.Time {
+	var first time.Time
+	analyzer.ScanLogFile(logFilePath, analyzer.ScanOptions{OnInvalidLine: func(int, error) {}}, func(e analyzer.Event) error {
+		if t := e.Time(); !t.IsZero() {
+			first = t
+			return analyzer.ErrStopScan
+		}
+		return nil
+	})
+	return first
+}
+
+func init() {
+	rootCmd.AddCommand(rotateCmd)
+
+	rotateCmd.Flags().String("max-size", "10MB", "日志达到此大小时轮转 (如 512K、10MB、1G；0 表示不限)")
+	rotateCmd.Flags().String("max-age", "30d", "最早的记录超过此时长时轮转 (如 7d、4w、72h；0 表示不限)")
+	rotateCmd.Flags().Bool("force", false, "忽略大小与时长限制，立即轮转")
+	// Not addDryRunFlag: rotating moves the log rather than planning edits, so
+	// there is no unified diff to print.
+	rotateCmd.Flags().Bool("dry-run", false, "只显示是否会轮转 (轮转移动整个日志文件，不显示 diff)，不移动任何文件")
+}
@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"rime-wanxiang-logger-go/internal/manager"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+)
+
+// allSchemas is the --schema value selecting every active schema.
+const allSchemas = "all"
+
+// addSchemaFlag registers the --schema flag shared by install, uninstall and status.
+func addSchemaFlag(c *cobra.Command) {
+	c.Flags().StringSlice("schema", nil, "要操作的输入方案 ID，可用逗号分隔多个；'all' 表示 schema_list 中所有已启用的方案")
+}
+
+// schemaFlag returns the schema IDs given with --schema, expanding "all".
+// Every returned schema is checked to exist in the user directory or build/.
+func schemaFlag(c *cobra.Command, m *manager.RimeManager) ([]string, error) {
+	values, _ := c.Flags().GetStringSlice("schema")
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	var ids []string
+	for _, v := range values {
+		v = strings.TrimSuffix(strings.TrimSpace(v), manager.SchemaFileSuffix)
+		if v == "" {
+			continue
+		}
+		if v == allSchemas {
+			active, err := activeSchemas(m)
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, active...)
+			continue
+		}
+		if !m.SchemaExists(v) {
+			return nil, fmt.Errorf("schema %q not found: %s", v, m.GetSchemaPath(v))
+		}
+		ids = append(ids, v)
+	}
+	return dedupe(ids), nil
+}
+
+// activeSchemas returns the discovered schemas that are enabled in schema_list.
+func activeSchemas(m *manager.RimeManager) ([]string, error) {
+	schemas, err := m.DiscoverSchemas()
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, s := range schemas {
+		if s.Active {
+			ids = append(ids, s.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no active schemas found in schema_list of %s", m.UserDirectory)
+	}
+	return ids, nil
+}
+
+// installTargetSchemas decides which schemas install should modify: those
+// given with --schema, else wanxiang if present, else the only schema found,
+// else the user's interactive choice.
+func installTargetSchemas(c *cobra.Command, m *manager.RimeManager, assumeYes bool) ([]string, error) {
+	ids, err := schemaFlag(c, m)
+	if err != nil || len(ids) > 0 {
+		return ids, err
+	}
+
+	if m.SchemaExists(manager.DefaultSchemaID) {
+		return []string{manager.DefaultSchemaID}, nil
+	}
+
+	schemas, err := m.DiscoverSchemas()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case len(schemas) == 0:
+		return nil, fmt.Errorf("no *.schema.yaml files found in %s; please deploy Rime at least once", m.UserDirectory)
+	case len(schemas) == 1:
+		return []string{schemas[0].ID}, nil
+	case assumeYes || !isInteractive():
+		return nil, fmt.Errorf("found %d schemas (%s); choose with --schema", len(schemas), schemaList(schemas))
+	}
+
+	labels := []string{"全部已启用的方案 (schema_list)"}
+	for _, s := range schemas {
+		label := s.ID
+		if s.Name != "" {
+			label += " - " + s.Name
+		}
+		if s.Active {
+			label += " (已启用)"
+		}
+		labels = append(labels, label)
+	}
+	prompt := promptui.Select{
+		Label: "请选择要安装日志记录器的输入方案",
+		Items: labels,
+	}
+	index, _, err := prompt.Run()
+	if err != nil {
+		return nil, fmt.Errorf("schema selection cancelled: %w", err)
+	}
+	if index == 0 {
+		return activeSchemas(m)
+	}
+	return []string{schemas[index-1].ID}, nil
+}
+
+// configuredTargetSchemas returns the schemas given with --schema, or every
+// discovered schema that currently contains the logger.
+func configuredTargetSchemas(c *cobra.Command, m *manager.RimeManager) ([]string, error) {
+	ids, err := schemaFlag(c, m)
+	if err != nil || len(ids) > 0 {
+		return ids, err
+	}
+	return m.ConfiguredSchemas()
+}
+
+func schemaList(schemas []manager.SchemaInfo) string {
+	ids := make([]string, len(schemas))
+	for i, s := range schemas {
+		ids[i] = s.ID
+	}
+	return strings.Join(ids, ", ")
+}
+
+func dedupe(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var out []string
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"rime-wanxiang-logger-go/internal/analyzer"
+	"rime-wanxiang-logger-go/internal/ui"
+
+	"github.com/spf13/cobra"
+)
+
+// sessionsCmd represents the sessions command
+var sessionsCmd = &cobra.Command{
+	Use:   "sessions",
+	Short: "List typing sessions with per-session accuracy.",
+	Long: `This command splits the log into sessions using the session_start and
+session_end events written by the logger, and lists each session with its
+duration, commit count, first choice hit rate and average rank. Sessions that
+never logged a session_end (for example after a crash) are still reported. In
+a log combined by 'merge' the sessions of each device are tracked separately.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ui.Section("输入会话分析")
+
+		rimeManager, err := newRimeManager()
+		if err != nil {
+			return fmt.Errorf("failed to initialize Rime manager: %w", err)
+		}
+
+		logFilePath, logFiles, err := logSources(cmd, rimeManager)
+		if err != nil {
+			return fmt.Errorf("failed to determine log file path: %w", err)
+		}
+
+		if len(logFiles) == 0 {
+			ui.Errorf("未找到日志文件: %s", logFilePath)
+			return nil
+		}
+
+		scanOpts, err := logScanOptions(cmd)
+		if err != nil {
+			return err
+		}
+
+		ui.Infof("正在分析日志文件: %s", describeLogSources(logFilePath, logFiles))
+
+		tracker := analyzer.NewSessionTracker()
+		err = analyzer.ScanLogs(logFiles, scanOpts, func(event analyzer.Event) error {
+			tracker.Add(event)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("分析过程中发生错误: %w", err)
+		}
+
+		sessions := tracker.Sessions()
+		if ui.Structured() {
+			return ui.Emit(sessionsReport{LogFile: logFilePath, Sessions: sessions})
+		}
+
+		if len(sessions) == 0 {
+			ui.Warnf("日志文件中未找到任何会话。")
+			return nil
+		}
+
+		ui.Subsection("会话列表")
+		merged := slices.ContainsFunc(sessions, func(s analyzer.Session) bool { return s.Device != "" })
+		headers := []string{"#", "输入方案", "开始时间", "时长", "上屏次数", "首选命中率", "平均排名", "状态"}
+		if merged {
+			headers = slices.Insert(headers, 1, "设备")
+		}
+		var rows [][]string
+		var unended int
+		for _, s := range sessions {
+			if !s.Ended {
+				unended++
+			}
+			row := []string{
+				fmt.Sprintf("%d", s.Index),
+				sessionSchemaLabel(s),
+				formatLocalTime(s.Start),
+				formatSessionDuration(s.Duration()),
+				fmt.Sprintf("%d", s.Result.TotalCommits),
+				formatSessionRate(s.Result),
+				formatSessionRank(s.Result),
+				sessionStatusLabel(s),
+			}
+			if merged {
+				row = slices.Insert(row, 1, deviceLabel(s.Device))
+			}
+			rows = append(rows, row)
+		}
+		ui.PrintTable(headers, rows)
+
+		ui.Subsection("汇总")
+		ui.PrintKV([][2]string{
+			{"会话总数", fmt.Sprintf("%d", len(sessions))},
+			{"未正常结束的会话", fmt.Sprintf("%d", unended)},
+		})
+
+		return nil
+	},
+}
+
+// sessionsReport is the structured (--format json|yaml|csv) form of the sessions output.
+type sessionsReport struct {
+	LogFile  string             `json:"log_file" yaml:"log_file"`
+	Sessions []analyzer.Session `json:"sessions" yaml:"sessions"`
+}
+
+// Table implements ui.Tabular with one row per session.
+func (r sessionsReport) Table() ([]string, [][]string) {
+	headers := append([]string{"index", "schema_id", "device", "start", "end", "duration_seconds", "ended", "implicit", "error_count"}, analysisResultColumns()...)
+	rows := make([][]string, 0, len(r.Sessions))
+	for _, s := range r.Sessions {
+		row := []string{
+			strconv.Itoa(s.Index),
+			s.SchemaID,
+			s.Device,
+			s.Start.Format(time.RFC3339),
+			s.End.Format(time.RFC3339),
+			strconv.FormatFloat(s.Duration().Seconds(), 'f', 0, 64),
+			strconv.FormatBool(s.Ended),
+			strconv.FormatBool(s.Implicit),
+			strconv.Itoa(s.ErrorCount),
+		}
+		rows = append(rows, append(row, analysisResultRow(s.Result)...))
+	}
+	return headers, rows
+}
+
+func sessionSchemaLabel(s analyzer.Session) string {
+	if s.SchemaID == "" {
+		return "-"
+	}
+	return s.SchemaID
+}
+
+func sessionStatusLabel(s analyzer.Session) string {
+	status := "完整"
+	if !s.Ended {
+		status = "未正常结束"
+	}
+	if s.Implicit {
+		status += " (无开始事件)"
+	}
+	if s.ErrorCount > 0 {
+		status += fmt.Sprintf(" / %d 个错误", s.ErrorCount)
+	}
+	return status
+}
+
+func formatLocalTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04:05")
+}
+
+func formatSessionDuration(d time.Duration) string {
+	return d.Round(time.Second).String()
+}
+
+func formatSessionRate(r analyzer.AnalysisResult) string {
+	if !r.HasValidSelections {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f%%", r.FirstChoiceHitRate)
+}
+
+func formatSessionRank(r analyzer.AnalysisResult) string {
+	if !r.HasValidSelections {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f", r.AverageRank)
+}
+
+func init() {
+	rootCmd.AddCommand(sessionsCmd)
+	addLogReadFlags(sessionsCmd)
+}
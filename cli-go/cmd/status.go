@@ -1,8 +1,12 @@
 package cmd
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
 
+	"rime-wanxiang-logger-go/internal/assets"
 	"rime-wanxiang-logger-go/internal/manager"
 	"rime-wanxiang-logger-go/internal/ui"
 
@@ -13,69 +17,290 @@ import (
 var statusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Check the current installation status.",
-	Long: `This command checks if the logger scripts are correctly installed,
-if the Rime schema is properly configured, and reports the location of the log file.`,
+	Long: `This command checks if the logger scripts are correctly installed and
+whether the logger script matches the embedded one (current, modified or
+outdated), if the Rime schema is properly configured, whether the deployed copy
+in build/ matches it (flagging a pending redeploy), and reports the location of the log file.
+Every schema containing the logger is checked unless --schema is given.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return checkStatus()
+		return checkStatus(cmd)
 	},
 }
 
+// Status check names, used as stable identifiers in structured output.
+const (
+	checkRimeDirectory   = "rime_user_directory"
+	checkLoggerScript    = "logger_script"
+	checkLoggerIntegrity = "logger_integrity"
+	checkConfigScript    = "config_script"
+	checkSchemaConfigure = "schema_configured"
+	checkBuildDeployed   = "build_configured"
+	checkLogFile         = "log_file"
+)
+
+// statusCheck is the outcome of a single status check.
+type statusCheck struct {
+	Name   string `json:"name" yaml:"name"`
+	OK     bool   `json:"ok" yaml:"ok"`
+	Schema string `json:"schema,omitempty" yaml:"schema,omitempty"`
+	Mode   string `json:"mode,omitempty" yaml:"mode,omitempty"`
+	Path   string `json:"path,omitempty" yaml:"path,omitempty"`
+	Detail string `json:"detail,omitempty" yaml:"detail,omitempty"`
+
+	message string // human-readable line for text output
+	warning bool   // render a failed check as a warning rather than an error
+}
+
+// statusReport is the structured (--format json|yaml|csv) form of the status output.
+type statusReport struct {
+	RimeUserDirectory string             `json:"rime_user_directory" yaml:"rime_user_directory"`
+	Frontends         []manager.Frontend `json:"frontends" yaml:"frontends"`
+	Checks            []statusCheck      `json:"checks" yaml:"checks"`
+}
+
+// Table implements ui.Tabular with one row per check.
+func (r statusReport) Table() ([]string, [][]string) {
+	rows := make([][]string, 0, len(r.Checks))
+	for _, c := range r.Checks {
+		rows = append(rows, []string{c.Name, strconv.FormatBool(c.OK), c.Schema, c.Mode, c.Path, c.Detail})
+	}
+	return []string{"name", "ok", "schema", "mode", "path", "detail"}, rows
+}
+
 // checkStatus performs comprehensive status checking (matching Python check_status method)
-func checkStatus() error {
+func checkStatus(cmd *cobra.Command) error {
 	ui.Section("Rime 日志记录器状态检查")
 
+	report := collectStatus(cmd)
+
+	if ui.Structured() {
+		return ui.Emit(report)
+	}
+
+	for _, f := range report.Frontends {
+		if f.Sync {
+			ui.Infof("检测到前端 %s 的同步文件夹: %s", f.Name, f.Directory)
+		} else {
+			ui.Infof("检测到前端 %s: %s", f.Name, f.Directory)
+		}
+	}
+
+	for _, c := range report.Checks {
+		switch {
+		case c.OK:
+			ui.Successf("%s", c.message)
+		case c.warning:
+			ui.Warnf("%s", c.message)
+		default:
+			ui.Errorf("%s", c.message)
+		}
+	}
+
+	return nil
+}
+
+// collectStatus runs every status check without printing anything.
+func collectStatus(cmd *cobra.Command) statusReport {
+	report := statusReport{Frontends: manager.DetectFrontends()}
+
 	// Initialize RimeManager
-	rimeManager, err := manager.NewRimeManager()
+	rimeManager, err := newRimeManager()
 	if err != nil {
-		ui.Errorf("未找到 Rime 用户目录。请问 Rime 是否已安装？")
-		return nil
+		report.Checks = append(report.Checks, statusCheck{
+			Name:    checkRimeDirectory,
+			Detail:  err.Error(),
+			message: "未找到 Rime 用户目录。请问 Rime 是否已安装？",
+		})
+		return report
 	}
 
-	ui.Successf("找到 Rime 用户目录: %s", rimeManager.UserDirectory)
+	report.RimeUserDirectory = rimeManager.UserDirectory
+	report.Checks = append(report.Checks, statusCheck{
+		Name:    checkRimeDirectory,
+		OK:      true,
+		Path:    rimeManager.UserDirectory,
+		message: "找到 Rime 用户目录: " + rimeManager.UserDirectory,
+	})
 
 	// Check Lua script files (matching Python loop)
 	loggerInstalled, configInstalled := rimeManager.CheckScriptsInstalled()
-
+	report.Checks = append(report.Checks,
+		scriptCheck(checkLoggerScript, filepath.Join(rimeManager.GetLuaDirectory(), manager.LoggerLuaFile), loggerInstalled),
+		scriptCheck(checkConfigScript, filepath.Join(rimeManager.GetLuaDirectory(), manager.ConfigLuaFile), configInstalled),
+	)
 	if loggerInstalled {
-		ui.Successf("找到脚本: %s", rimeManager.GetLuaDirectory()+"/"+manager.LoggerLuaFile)
-	} else {
-		ui.Errorf("未找到脚本: %s", rimeManager.GetLuaDirectory()+"/"+manager.LoggerLuaFile)
-	}
-
-	if configInstalled {
-		ui.Successf("找到脚本: %s", rimeManager.GetLuaDirectory()+"/"+manager.ConfigLuaFile)
-	} else {
-		ui.Errorf("未找到脚本: %s", rimeManager.GetLuaDirectory()+"/"+manager.ConfigLuaFile)
+		report.Checks = append(report.Checks, integrityCheck(rimeManager))
 	}
 
 	// Check schema configuration (matching Python logic)
-	schemaPath := rimeManager.GetSchemaPath()
-	if _, err := os.Stat(schemaPath); os.IsNotExist(err) {
-		ui.Errorf("未找到输入方案文件: %s", schemaPath)
-	} else {
-		configured, err := rimeManager.CheckSchemaConfigured()
-		if err != nil {
-			ui.Warnf("无法读取输入方案文件。错误: %v", err)
-		} else if configured {
-			ui.Successf("输入方案 '%s' 已为日志记录器正确配置。", manager.SchemaYamlFile)
-		} else {
-			ui.Errorf("输入方案 '%s' 尚未配置。", manager.SchemaYamlFile)
+	schemaIDs, err := configuredTargetSchemas(cmd, rimeManager)
+	if err != nil {
+		report.Checks = append(report.Checks, statusCheck{
+			Name:    checkSchemaConfigure,
+			Detail:  err.Error(),
+			warning: true,
+			message: "无法确定要检查的输入方案。错误: " + err.Error(),
+		})
+	} else if len(schemaIDs) == 0 {
+		// Nothing configured yet: report on the default schema as before
+		schemaIDs = []string{manager.DefaultSchemaID}
+	}
+	for _, schemaID := range schemaIDs {
+		report.Checks = append(report.Checks, schemaStatusCheck(rimeManager, schemaID))
+		if check, ok := buildStatusCheck(rimeManager, schemaID); ok {
+			report.Checks = append(report.Checks, check)
 		}
 	}
 
 	// Check log file existence (matching Python logic)
+	logCheck := statusCheck{Name: checkLogFile}
 	logFilePath, err := rimeManager.GetLogFilePath()
+	switch {
+	case err != nil:
+		logCheck.Detail = err.Error()
+		logCheck.warning = true
+		logCheck.message = "无法确定日志文件路径。错误: " + err.Error()
+	case rimeManager.LogFileExists():
+		logCheck.OK = true
+		logCheck.Path = logFilePath
+		logCheck.message = "找到日志文件: " + logFilePath
+	default:
+		logCheck.Path = logFilePath
+		logCheck.message = "在 '" + logFilePath + "' 未找到日志文件。请打字以生成日志。"
+		if archives, _ := rimeManager.LogArchives(); len(archives) > 0 {
+			// Just rotated: the logger starts a new file with the next entry
+			logCheck.OK = true
+			logCheck.Detail = fmt.Sprintf("%d archives", len(archives))
+			logCheck.message = fmt.Sprintf("日志已轮转，'%s' 中有 %d 个归档，新的日志文件将在下次打字时生成。", rimeManager.GetLogsDirectory(), len(archives))
+		}
+	}
+	report.Checks = append(report.Checks, logCheck)
+
+	return report
+}
+
+func schemaStatusCheck(rimeManager *manager.RimeManager, schemaID string) statusCheck {
+	schemaPath := rimeManager.GetSchemaPath(schemaID)
+	schemaFile := manager.SchemaFileName(schemaID)
+	customFile := manager.CustomFileName(schemaID)
+	check := statusCheck{Name: checkSchemaConfigure, Schema: schemaID, Path: schemaPath}
+
+	patched, err := rimeManager.CheckPatchConfigured(schemaID)
 	if err != nil {
-		ui.Warnf("无法确定日志文件路径。错误: %v", err)
-	} else if rimeManager.LogFileExists() {
-		ui.Successf("找到日志文件: %s", logFilePath)
-	} else {
-		ui.Errorf("在 '%s' 未找到日志文件。请打字以生成日志。", logFilePath)
+		check.Path = rimeManager.GetCustomPatchPath(schemaID)
+		check.Detail = err.Error()
+		check.warning = true
+		check.message = "无法读取补丁文件。错误: " + err.Error()
+		return check
 	}
 
-	return nil
+	if _, err := os.Stat(schemaPath); os.IsNotExist(err) {
+		if patched {
+			check.OK = true
+			check.Mode = manager.ModePatch
+			check.Path = rimeManager.GetCustomPatchPath(schemaID)
+			check.message = "输入方案 '" + schemaID + "' 已通过补丁 '" + customFile + "' 配置日志记录器。"
+			return check
+		}
+		check.message = "未找到输入方案文件: " + schemaPath
+		return check
+	}
+
+	configured, err := rimeManager.CheckSchemaConfigured(schemaID)
+	switch {
+	case err != nil:
+		check.Detail = err.Error()
+		check.warning = true
+		check.message = "无法读取输入方案文件。错误: " + err.Error()
+	case configured && patched:
+		// Rime would load the processor twice and log every event twice
+		check.Mode = manager.ModeSchema + "+" + manager.ModePatch
+		check.Detail = "configured in both " + schemaFile + " and " + customFile
+		check.warning = true
+		check.message = "输入方案 '" + schemaFile + "' 与补丁 '" + customFile + "' 同时配置了日志记录器，事件会被重复记录。请移除其中之一。"
+	case configured:
+		check.OK = true
+		check.Mode = manager.ModeSchema
+		check.message = "输入方案 '" + schemaFile + "' 已为日志记录器正确配置。"
+	case patched:
+		check.OK = true
+		check.Mode = manager.ModePatch
+		check.Path = rimeManager.GetCustomPatchPath(schemaID)
+		check.message = "输入方案 '" + schemaID + "' 已通过补丁 '" + customFile + "' 配置日志记录器。"
+	default:
+		check.message = "输入方案 '" + schemaFile + "' 尚未配置。"
+	}
+	return check
+}
+
+// buildStatusCheck compares the deployed schema in build/ with its sources.
+// It reports false when there is nothing to say: the logger is neither
+// configured nor deployed.
+func buildStatusCheck(rimeManager *manager.RimeManager, schemaID string) (statusCheck, bool) {
+	check := statusCheck{Name: checkBuildDeployed, Schema: schemaID, Path: rimeManager.GetBuildSchemaPath(schemaID), warning: true}
+	status, err := rimeManager.CheckBuildStatus(schemaID)
+	if err != nil {
+		check.Detail = err.Error()
+		check.message = "无法检查已部署的输入方案。错误: " + err.Error()
+		return check, true
+	}
+
+	check.Detail = string(status.State)
+	switch {
+	case status.State == manager.DeployPending && status.Configured:
+		check.message = "输入方案 '" + schemaID + "' 已配置日志记录器，但尚未重新部署，Rime 目前不会加载它。请重新部署 Rime。"
+	case status.State == manager.DeployPending:
+		check.message = "日志记录器已从输入方案 '" + schemaID + "' 中移除，但部署后的方案仍包含它。请重新部署 Rime。"
+	case status.State == manager.DeployNotDeployed:
+		return check, false
+	case status.Deployed:
+		check.OK = true
+		check.message = "部署后的输入方案 '" + schemaID + "' 包含日志记录器。"
+	default:
+		return check, false
+	}
+	return check, true
+}
+
+// integrityCheck compares the installed logger script with the embedded one.
+func integrityCheck(rimeManager *manager.RimeManager) statusCheck {
+	check := statusCheck{Name: checkLoggerIntegrity, warning: true}
+	integrity, err := rimeManager.CheckLoggerIntegrity(assets.LoggerScript)
+	if err != nil {
+		check.Detail = err.Error()
+		check.message = "无法校验日志脚本。错误: " + err.Error()
+		return check
+	}
+
+	check.Path = integrity.Path
+	check.Detail = string(integrity.State)
+	version := integrity.Version
+	if version == "" {
+		version = "未知版本"
+	}
+	switch integrity.State {
+	case manager.ScriptCurrent:
+		check.OK = true
+		check.message = "日志脚本与内置版本一致 (" + version + ")。"
+	case manager.ScriptOutdated:
+		check.message = "日志脚本版本过旧 (" + version + ")。请运行 'upgrade' 升级。"
+	case manager.ScriptModified:
+		check.message = "日志脚本已被修改 (" + version + ", sha256 " + integrity.SHA256[:12] + ")。可运行 'repair' 恢复原始脚本。"
+	default:
+		check.warning = false
+		check.message = "未找到脚本: " + integrity.Path
+	}
+	return check
+}
+
+func scriptCheck(name, path string, installed bool) statusCheck {
+	if installed {
+		return statusCheck{Name: name, OK: true, Path: path, message: "找到脚本: " + path}
+	}
+	return statusCheck{Name: name, Path: path, message: "未找到脚本: " + path}
 }
 
 func init() {
 	rootCmd.AddCommand(statusCmd)
+	addSchemaFlag(statusCmd)
 }
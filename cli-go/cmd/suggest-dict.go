@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"rime-wanxiang-logger-go/internal/analyzer"
+	"rime-wanxiang-logger-go/internal/manager"
+	"rime-wanxiang-logger-go/internal/ui"
+
+	"github.com/spf13/cobra"
+)
+
+var suggestDictCmd = &cobra.Command{
+	Use:   "suggest-dict",
+	Short: "Generate a custom_phrase.txt or dict.yaml patch from repeated mispredictions.",
+	Long: `This command finds texts that were repeatedly chosen over Rime's first
+candidate and turns them into dictionary entries, keyed by the input code that
+was typed (source_input_buffer or input_sequence_at_commit). The entries are
+weighted by how often they were chosen.
+
+With --type phrase (default) they are merged into custom_phrase.txt in the
+Rime user directory; existing phrases are left untouched. With --type dict
+they are written to a standalone <name>.dict.yaml, to be added to
+import_tables of the schema's main dictionary; if that file exists, its
+entries are kept and only new ones are appended. The previous file is backed
+up first, and --dry-run prints the change without writing it. Redeploy Rime
+afterwards.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ui.Section("生成词库补丁")
+
+		dictType, _ := cmd.Flags().GetString("type")
+		if dictType != "phrase" && dictType != "dict" {
+			return fmt.Errorf("unsupported --type %q (expected phrase or dict)", dictType)
+		}
+		minCount, _ := cmd.Flags().GetInt("min-count")
+		minRank, _ := cmd.Flags().GetFloat64("min-mean-rank")
+		dictName, _ := cmd.Flags().GetString("dict-name")
+		outPath, _ := cmd.Flags().GetString("output")
+		printOnly, _ := cmd.Flags().GetBool("print")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		rimeManager, err := newRimeManager()
+		if err != nil {
+			return fmt.Errorf("could not initialize Rime manager: %w", err)
+		}
+
+		logFilePath, logFiles, err := logSources(cmd, rimeManager)
+		if err != nil {
+			return fmt.Errorf("failed to determine log file path: %w", err)
+		}
+
+		if len(logFiles) == 0 {
+			ui.Errorf("未找到日志文件: %s", logFilePath)
+			return nil
+		}
+
+		scanOpts, err := logScanOptions(cmd)
+		if err != nil {
+			return err
+		}
+
+		ui.Infof("正在分析日志文件: %s", describeLogSources(logFilePath, logFiles))
+
+		aggregator := analyzer.NewMissAggregator()
+		err = analyzer.ScanCommits(logFiles, scanOpts, func(event *analyzer.TextCommittedEvent) error {
+			aggregator.Add(event)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("分析过程中发生错误: %w", err)
+		}
+
+		suggestions := analyzer.SuggestDictEntries(aggregator.Pairs(), analyzer.SuggestOptions{
+			MinCount: minCount,
+			MinRank:  minRank,
+		})
+
+		entries := make([]manager.DictEntry, 0, len(suggestions))
+		for _, s := range suggestions {
+			code := manager.CustomPhraseCode(s.Code)
+			if dictType == "dict" {
+				code = manager.DictCode(s.Code)
+			}
+			entries = append(entries, manager.DictEntry{Text: s.Text, Code: code, Weight: s.Count})
+		}
+
+		if outPath == "" {
+			outPath = rimeManager.GetCustomPhrasePath()
+			if dictType == "dict" {
+				outPath = rimeManager.GetDictPath(dictName)
+			}
+		}
+
+		report := suggestDictReport{OutputFile: outPath, Type: dictType, Suggestions: suggestions}
+		if len(entries) == 0 {
+			if ui.Structured() {
+				return ui.Emit(report)
+			}
+			ui.Successf("没有出现次数不少于 %d 次、平均排名不低于 %.1f 的预测错误，无需生成补丁。", minCount, minRank)
+			return nil
+		}
+
+		if !ui.Structured() {
+			ui.Subsection(fmt.Sprintf("建议条目 (%d)", len(entries)))
+			rows := make([][]string, 0, len(entries))
+			for i, e := range entries {
+				rows = append(rows, []string{e.Text, e.Code, strconv.Itoa(e.Weight), fmt.Sprintf("%.2f", suggestions[i].MeanRank)})
+			}
+			ui.PrintTable([]string{"词语", "编码", "权重", "平均排名"}, rows)
+		}
+		if printOnly {
+			if ui.Structured() {
+				return ui.Emit(report)
+			}
+			return nil
+		}
+
+		plan := manager.NewPlan()
+		var added []manager.DictEntry
+		if dictType == "dict" {
+			added, err = manager.PlanDictEntries(plan, outPath, dictName, entries)
+		} else {
+			added, err = manager.PlanCustomPhrases(plan, outPath, entries)
+		}
+		if err != nil {
+			return err
+		}
+
+		if dryRun {
+			return showPlan(plan)
+		}
+		if plan.Empty() {
+			if ui.Structured() {
+				return ui.Emit(report)
+			}
+			ui.Infof("所有建议条目均已存在于 %s，无需更改。", outPath)
+			return nil
+		}
+
+		backup, err := applyPlan(rimeManager, plan, "suggest-dict")
+		if err != nil {
+			return fmt.Errorf("failed to apply changes: %w", err)
+		}
+		if ui.Structured() {
+			return ui.Emit(report)
+		}
+
+		ui.Successf("已写入 %d 个新条目到: %s", len(added), outPath)
+		if dictType == "dict" {
+			ui.Infof("请在主词典的 import_tables 中加入 '%s' 以启用这些条目。", dictName)
+		}
+		if id := backup.ID(); id != "" {
+			ui.Infof("修改前的文件已备份 (ID: %s)，可使用 'backup restore %s' 回滚。", id, id)
+		}
+		ui.Warnf("重要提示: 您必须立即 '重新部署' Rime才能使更改生效。")
+
+		return nil
+	},
+}
+
+// suggestDictReport is the structured (--format json|yaml|csv) form of the suggest-dict output.
+type suggestDictReport struct {
+	OutputFile  string                    `json:"output_file" yaml:"output_file"`
+	Type        string                    `json:"type" yaml:"type"`
+	Suggestions []analyzer.DictSuggestion `json:"suggestions" yaml:"suggestions"`
+}
+
+// Table implements ui.Tabular with one row per suggestion.
+func (r suggestDictReport) Table() ([]string, [][]string) {
+	rows := make([][]string, 0, len(r.Suggestions))
+	for _, s := range r.Suggestions {
+		rows = append(rows, []string{s.Text, s.Code, strconv.Itoa(s.Count), strconv.FormatFloat(s.MeanRank, 'f', 4, 64)})
+	}
+	return []string{"text", "code", "count", "mean_rank"}, rows
+}
+
+func init() {
+	rootCmd.AddCommand(suggestDictCmd)
+
+	suggestDictCmd.Flags().String("type", "phrase", "补丁类型: phrase (custom_phrase.txt) 或 dict (*.dict.yaml)")
+	suggestDictCmd.Flags().Int("min-count", 3, "仅包含被选择次数不少于该值的条目")
+	suggestDictCmd.Flags().Float64("min-mean-rank", 1, "仅包含平均选择排名不低于该值的条目")
+	suggestDictCmd.Flags().String("dict-name", "input_habit_suggestions", "--type dict 时生成的词典名称")
+	suggestDictCmd.Flags().StringP("output", "o", "", "输出文件路径 (默认写入 Rime 用户目录)")
+	suggestDictCmd.Flags().Bool("print", false, "仅打印建议条目，不写入文件")
+	addDryRunFlag(suggestDictCmd)
+	addLogReadFlags(suggestDictCmd)
+}
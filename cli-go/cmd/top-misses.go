@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"rime-wanxiang-logger-go/internal/analyzer"
+	"rime-wanxiang-logger-go/internal/ui"
+
+	"github.com/spf13/cobra"
+)
+
+var topMissesCmd = &cobra.Command{
+	Use:   "top-misses",
+	Short: "Show the most frequent misprediction pairs.",
+	Long: `This command groups mispredictions by (input code, predicted first candidate,
+actually chosen text) and prints the most frequent pairs with their count, mean
+selected rank and when they were first and last seen. These are the entries
+dictionary contributors should look at first.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ui.Section("高频预测错误")
+
+		rimeManager, err := newRimeManager()
+		if err != nil {
+			return fmt.Errorf("could not initialize Rime manager: %w", err)
+		}
+
+		logFilePath, logFiles, err := logSources(cmd, rimeManager)
+		if err != nil {
+			return fmt.Errorf("failed to determine log file path: %w", err)
+		}
+
+		if len(logFiles) == 0 {
+			ui.Errorf("未找到日志文件: %s", logFilePath)
+			return nil
+		}
+
+		scanOpts, err := logScanOptions(cmd)
+		if err != nil {
+			return err
+		}
+		limit, _ := cmd.Flags().GetInt("limit")
+		minCount, _ := cmd.Flags().GetInt("min-count")
+
+		ui.Infof("正在分析日志文件: %s", describeLogSources(logFilePath, logFiles))
+
+		aggregator := analyzer.NewMissAggregator()
+		err = analyzer.ScanCommits(logFiles, scanOpts, func(event *analyzer.TextCommittedEvent) error {
+			aggregator.Add(event)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("分析过程中发生错误: %w", err)
+		}
+
+		top := aggregator.Top(limit, minCount)
+		if ui.Structured() {
+			return ui.Emit(missPairsReport(top))
+		}
+
+		if aggregator.MissCount() == 0 {
+			ui.Successf("太好了！未发现预测错误。您的输入法表现完美！")
+			return nil
+		}
+
+		ui.Infof("在 %d 次总提交中发现 %d 次预测错误，共 %d 种组合", aggregator.TotalCommits(), aggregator.MissCount(), aggregator.PairCount())
+		if len(top) == 0 {
+			ui.Warnf("没有出现次数不少于 %d 次的预测错误组合。", minCount)
+			return nil
+		}
+
+		ui.Subsection(fmt.Sprintf("前 %d 个预测错误组合", len(top)))
+		headers := []string{"#", "用户输入", "程序预测", "实际选择", "次数", "平均排名", "首次出现", "最近出现"}
+		rows := make([][]string, 0, len(top))
+		for i, p := range top {
+			rows = append(rows, []string{
+				strconv.Itoa(i + 1),
+				p.Input,
+				p.Predicted,
+				p.Chosen,
+				strconv.Itoa(p.Count),
+				fmt.Sprintf("%.2f", p.MeanRank),
+				formatLocalTime(p.FirstSeen),
+				formatLocalTime(p.LastSeen),
+			})
+		}
+		ui.PrintTable(headers, rows)
+
+		return nil
+	},
+}
+
+// missPairsReport is the structured (--format json|yaml|csv) form of the top-misses output.
+type missPairsReport []analyzer.MissPair
+
+// Table implements ui.Tabular with one row per pair.
+func (r missPairsReport) Table() ([]string, [][]string) {
+	headers := []string{"input", "predicted", "chosen", "count", "mean_rank", "first_seen", "last_seen"}
+	rows := make([][]string, 0, len(r))
+	for _, p := range r {
+		rows = append(rows, []string{
+			p.Input,
+			p.Predicted,
+			p.Chosen,
+			strconv.Itoa(p.Count),
+			strconv.FormatFloat(p.MeanRank, 'f', 4, 64),
+			analyzer.FormatCSVTime(p.FirstSeen),
+			analyzer.FormatCSVTime(p.LastSeen),
+		})
+	}
+	return headers, rows
+}
+
+func init() {
+	rootCmd.AddCommand(topMissesCmd)
+
+	topMissesCmd.Flags().IntP("limit", "n", 20, "显示的组合数量 (0 表示全部)")
+	topMissesCmd.Flags().Int("min-count", 1, "仅显示出现次数不少于该值的组合")
+	addLogReadFlags(topMissesCmd)
+}
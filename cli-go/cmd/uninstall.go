@@ -1,9 +1,11 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"rime-wanxiang-logger-go/internal/manager"
 	"rime-wanxiang-logger-go/internal/ui"
@@ -17,61 +19,175 @@ var uninstallCmd = &cobra.Command{
 	Use:   "uninstall",
 	Short: "Uninstall the logger scripts from the Rime user directory.",
 	Long: `This command removes the Lua scripts and warns the user to revert changes
-made to the Rime schema file, effectively disabling the logger.`,
+made to the Rime schema file, effectively disabling the logger. Every schema
+containing the logger is reverted unless --schema is given. Entries added to
+<schema>.custom.yaml by install --mode patch are removed as well.
+With --dry-run every planned change is printed as a unified diff and nothing
+is removed.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ui.Section("开始卸载日志记录器")
 
-		rimeManager, err := manager.NewRimeManager()
+		assumeYes, _ := cmd.Flags().GetBool("yes")
+		keepConfig, _ := cmd.Flags().GetBool("keep-config")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		rimeManager, err := newRimeManager()
 		if err != nil {
 			return fmt.Errorf("could not initialize Rime manager: %w", err)
 		}
 		ui.Successf("检测到 Rime 用户目录: %s", rimeManager.UserDirectory)
 
-		loggerScriptPath := filepath.Join(rimeManager.GetLuaDirectory(), "input_habit_logger.lua")
-		configScriptPath := filepath.Join(rimeManager.GetLuaDirectory(), "input_habit_logger_config.lua")
+		loggerScriptPath := filepath.Join(rimeManager.GetLuaDirectory(), manager.LoggerLuaFile)
+		configScriptPath := filepath.Join(rimeManager.GetLuaDirectory(), manager.ConfigLuaFile)
+
+		schemaIDs, err := configuredTargetSchemas(cmd, rimeManager)
+		if err != nil {
+			return err
+		}
+
+		// Scripts stay installed while schemas outside --schema still use them
+		var stillUsed []string
+		if configured, err := rimeManager.ConfiguredSchemas(); err == nil {
+			targets := make(map[string]bool, len(schemaIDs))
+			for _, id := range schemaIDs {
+				targets[id] = true
+			}
+			for _, id := range configured {
+				if !targets[id] {
+					stillUsed = append(stillUsed, id)
+				}
+			}
+		}
+
+		// Refuse before changing anything if the config prompt could not be answered
+		_, configErr := os.Stat(configScriptPath)
+		if len(stillUsed) == 0 && configErr == nil && !keepConfig && !assumeYes && !dryRun && !isInteractive() {
+			return errNotInteractive
+		}
+
+		// Every change is planned first, so --dry-run shows exactly what a real run does
+		plan := manager.NewPlan()
 
 		// 步骤 1: 移除 Lua 脚本...
-		ui.Subsection("步骤 1: 移除 Lua 脚本...")
+		ui.Subsection("步骤 1: 准备移除 Lua 脚本...")
 
-		if _, err := os.Stat(loggerScriptPath); err == nil {
-			if err := os.Remove(loggerScriptPath); err != nil {
-				return fmt.Errorf("failed to delete 'input_habit_logger.lua': %w", err)
+		if len(stillUsed) > 0 {
+			ui.Infof("输入方案 %s 仍在使用日志记录器，保留 Lua 脚本。", strings.Join(stillUsed, ", "))
+		} else if err := planRemoveLoggerScripts(plan, loggerScriptPath, configScriptPath, configErr == nil, keepConfig, assumeYes, dryRun); err != nil {
+			return err
+		}
+
+		// 步骤 2: 恢复输入方案文件...
+		ui.Subsection("步骤 2: 准备恢复输入方案文件...")
+		if len(schemaIDs) == 0 {
+			ui.Infof("没有输入方案配置了日志记录器，无需更改。")
+		}
+		for _, schemaID := range schemaIDs {
+			patched, _ := rimeManager.CheckPatchConfigured(schemaID)
+			if patched {
+				if err := planPatchUninstall(rimeManager, plan, schemaID); err != nil {
+					return err
+				}
+			}
+			// Patch-only installs may not even have a schema file in the user directory
+			if configured, _ := rimeManager.CheckSchemaConfigured(schemaID); patched && !configured {
+				continue
+			}
+			reverted, err := rimeManager.PlanSchemaUninstall(plan, schemaID)
+			switch {
+			case err != nil:
+				return fmt.Errorf("failed to revert schema file: %w", err)
+			case reverted:
+				ui.Infof("将从 '%s' 中移除日志记录器配置。", manager.SchemaFileName(schemaID))
+			default:
+				ui.Infof("'%s' 中未找到日志记录器配置，无需更改。", manager.SchemaFileName(schemaID))
 			}
-			ui.Successf("已移除: %s", loggerScriptPath)
-		} else {
-			ui.Warnf("未找到: %s", loggerScriptPath)
 		}
 
-		// 询问是否移除配置文件
-		if _, err := os.Stat(configScriptPath); err == nil {
+		if dryRun {
+			return showPlan(plan)
+		}
+
+		// 步骤 3: 写入更改 (修改前的文件会先备份)
+		ui.Subsection("步骤 3: 写入更改...")
+		backup, err := applyPlan(rimeManager, plan, "uninstall")
+		if err != nil {
+			return fmt.Errorf("failed to apply changes: %w", err)
+		}
+		ui.Section("卸载完成！")
+		if id := backup.ID(); id != "" {
+			ui.Infof("修改前的文件已备份 (ID: %s)，可使用 'backup restore %s' 回滚。", id, id)
+		}
+		return finishRedeploy(cmd, rimeManager, schemaIDs)
+	},
+}
+
+// planRemoveLoggerScripts plans deleting the logger script and, depending on
+// the flags or the user's answer, the config script. A dry run never prompts
+// and keeps the config unless --yes is given.
+func planRemoveLoggerScripts(plan *manager.Plan, loggerScriptPath, configScriptPath string, configExists, keepConfig, assumeYes, dryRun bool) error {
+	if _, err := os.Stat(loggerScriptPath); err == nil {
+		if err := plan.Remove(loggerScriptPath); err != nil {
+			return err
+		}
+	} else {
+		ui.Warnf("未找到: %s", loggerScriptPath)
+	}
+
+	// 询问是否移除配置文件
+	if configExists {
+		removeConfig := false
+		switch {
+		case keepConfig:
+		case assumeYes:
+			removeConfig = true
+		case dryRun:
+			ui.Infof("实际运行时会询问是否移除配置文件 '%s'。", filepath.Base(configScriptPath))
+			return nil
+		default:
 			confirm := promptui.Prompt{
 				Label:     fmt.Sprintf("是否也移除配置文件 '%s'？", filepath.Base(configScriptPath)),
 				IsConfirm: true,
 			}
-			if _, err := confirm.Run(); err == nil {
-				if err := os.Remove(configScriptPath); err != nil {
-					return fmt.Errorf("failed to delete 'input_habit_logger_config.lua': %w", err)
-				}
-				ui.Successf("已移除: %s", configScriptPath)
-			} else {
-				ui.Warnf("已保留配置文件: %s", configScriptPath)
+			_, err := confirm.Run()
+			removeConfig = err == nil
+		}
+
+		if removeConfig {
+			if err := plan.Remove(configScriptPath); err != nil {
+				return err
 			}
 		} else {
-			ui.Warnf("未找到: %s", configScriptPath)
+			ui.Warnf("已保留配置文件: %s", configScriptPath)
 		}
+	} else {
+		ui.Warnf("未找到: %s", configScriptPath)
+	}
 
-		// 步骤 2: 恢复输入方案文件...
-		ui.Subsection("步骤 2: 恢复输入方案文件...")
-		if err := rimeManager.RevertSchemaForUninstall(); err != nil {
-			return fmt.Errorf("failed to revert schema file: %w", err)
-		}
-		ui.Warnf("重要提示: 您必须立即 '重新部署' Rime才能使更改生效。")
+	return nil
+}
 
-		ui.Section("卸载完成！")
+// planPatchUninstall plans removing the logger entry from <schema>.custom.yaml.
+func planPatchUninstall(rimeManager *manager.RimeManager, plan *manager.Plan, schemaID string) error {
+	changed, err := rimeManager.PlanPatchUninstall(plan, schemaID)
+	switch {
+	case errors.Is(err, manager.ErrPatchNotManaged):
+		ui.Warnf("'%s' 中的日志记录器不是由本工具添加的，请手动移除 '%s'。", manager.CustomFileName(schemaID), manager.LoggerProcessor)
 		return nil
-	},
+	case err != nil:
+		return fmt.Errorf("failed to revert patch file: %w", err)
+	case changed:
+		ui.Infof("将从补丁 '%s' 中移除日志记录器。", manager.CustomFileName(schemaID))
+	}
+	return nil
 }
 
 func init() {
 	rootCmd.AddCommand(uninstallCmd)
+
+	uninstallCmd.Flags().BoolP("yes", "y", false, "不进行任何询问，同时移除配置文件")
+	uninstallCmd.Flags().Bool("keep-config", false, "保留配置文件 input_habit_logger_config.lua，不进行询问")
+	addSchemaFlag(uninstallCmd)
+	addDryRunFlag(uninstallCmd)
+	addRedeployFlag(uninstallCmd)
 }
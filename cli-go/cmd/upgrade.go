@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"rime-wanxiang-logger-go/internal/assets"
+	"rime-wanxiang-logger-go/internal/manager"
+	"rime-wanxiang-logger-go/internal/ui"
+
+	"github.com/spf13/cobra"
+)
+
+// upgradeCmd represents the upgrade command
+var upgradeCmd = &cobra.Command{
+	Use:   "upgrade",
+	Short: "Upgrade the installed logger script and keep the user's config.",
+	Long: `This command reads the version header of the installed input_habit_logger.lua,
+compares it with the version embedded in this tool and replaces the script.
+Unlike install, the config is not reset: preset_choice and every setting of
+every preset (including those changed with 'config set') are carried over into
+the new input_habit_logger_config.lua template; anything else is reported as
+not kept. Schema files are not touched.
+Downgrading to an older embedded version requires --force.
+With --dry-run every planned change is printed as a unified diff and nothing
+is written.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ui.Section("升级日志记录器")
+
+		force, _ := cmd.Flags().GetBool("force")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		rimeManager, err := newRimeManager()
+		if err != nil {
+			return fmt.Errorf("failed to initialize Rime manager: %w", err)
+		}
+		ui.Successf("找到 Rime 目录: %s", rimeManager.UserDirectory)
+
+		loggerScriptPath := filepath.Join(rimeManager.GetLuaDirectory(), manager.LoggerLuaFile)
+		if _, err := os.Stat(loggerScriptPath); os.IsNotExist(err) {
+			ui.Errorf("未找到已安装的脚本: %s", loggerScriptPath)
+			return errors.New("logger is not installed, run 'install' first")
+		}
+
+		embedded, err := manager.ParseLoggerVersion(assets.LoggerScript)
+		if err != nil {
+			return fmt.Errorf("embedded logger script: %w", err)
+		}
+
+		installed, err := rimeManager.InstalledLoggerVersion()
+		switch {
+		case errors.Is(err, manager.ErrUnknownVersion):
+			ui.Warnf("无法识别已安装脚本的版本，将直接替换为 %s。", embedded)
+		case err != nil:
+			return fmt.Errorf("failed to read installed logger script: %w", err)
+		default:
+			ui.Infof("已安装版本: %s", installed)
+			ui.Infof("内置版本:   %s", embedded)
+			if installed.Compare(embedded) > 0 && !force {
+				ui.Warnf("已安装的脚本比本工具内置的更新，如需降级请使用 --force。")
+				return fmt.Errorf("installed logger %s is newer than embedded %s", installed, embedded)
+			}
+		}
+
+		plan := manager.NewPlan()
+		migration, err := rimeManager.PlanUpgrade(plan, assets.LoggerScript, assets.ConfigScript)
+		if err != nil {
+			return fmt.Errorf("failed to plan upgrade: %w", err)
+		}
+
+		ui.Subsection("保留的配置项")
+		if len(migration.Carried) == 0 {
+			ui.Infof("没有需要保留的自定义配置。")
+		}
+		for _, s := range migration.Carried {
+			ui.Infof("%s = %s", s.Key, s.Value)
+		}
+		for _, s := range migration.Dropped {
+			ui.Warnf("无法迁移 '%s'，该设置 (%s) 将不会保留。", s.Key, s.Value)
+		}
+
+		if dryRun {
+			return showPlan(plan)
+		}
+
+		if plan.Empty() {
+			ui.Section("已是最新版本，无需升级。")
+			return nil
+		}
+
+		ui.Subsection("写入更改...")
+		backup, err := applyPlan(rimeManager, plan, "upgrade")
+		if err != nil {
+			return fmt.Errorf("failed to apply changes: %w", err)
+		}
+
+		ui.Section("升级成功！")
+		if id := backup.ID(); id != "" {
+			ui.Infof("修改前的文件已备份 (ID: %s)，可使用 'backup restore %s' 回滚。", id, id)
+		}
+		return finishRedeploy(cmd, rimeManager, configuredSchemasOrNone(rimeManager))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(upgradeCmd)
+
+	upgradeCmd.Flags().Bool("force", false, "即使已安装的脚本版本更新也进行替换 (降级)")
+	addDryRunFlag(upgradeCmd)
+	addRedeployFlag(upgradeCmd)
+}
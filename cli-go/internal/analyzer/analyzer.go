@@ -2,161 +2,158 @@
 package analyzer
 
 import (
-	"bufio"
 	"encoding/csv"
-	"encoding/json"
 	"fmt"
 	"os"
+	"sort"
 	"strconv"
 )
 
-// LogEvent defines the structure of a single log entry in the JSONL file.
-// Fields are tagged for JSON unmarshalling and match the Lua script output.
-type LogEvent struct {
-	EventType             string   `json:"event_type"`
-	SelectedCandidateRank *int     `json:"selected_candidate_rank,omitempty"`
-	CommittedText         string   `json:"committed_text,omitempty"`
-	SourceFirstCandidate  string   `json:"source_first_candidate,omitempty"`
-	InputSequenceAtCommit string   `json:"input_sequence_at_commit,omitempty"`
-	SourceCandidatesList  []string `json:"source_candidates_list,omitempty"`
-	SourceInputBuffer     string   `json:"source_input_buffer,omitempty"`
-	SelectionMethod       string   `json:"selection_method,omitempty"`
-	Timestamp             string   `json:"timestamp,omitempty"`
-}
-
 // AnalysisResult holds the calculated metrics from the log file analysis.
 // This matches the Python version's analysis output.
 type AnalysisResult struct {
 	// Raw data counts
-	TotalCommits    int
-	TotalSelections int
-	RawInputCommits int
+	TotalCommits    int `json:"total_commits" yaml:"total_commits"`
+	TotalSelections int `json:"total_selections" yaml:"total_selections"`
+	RawInputCommits int `json:"raw_input_commits" yaml:"raw_input_commits"`
 
 	// Accuracy metrics
-	FirstChoiceCount     int
-	Top3Count            int
-	FirstChoiceHitRate   float64
-	Top3HitRate          float64
-	AverageRank          float64
-	OverallAccuracyScore float64
-	DirectInputRate      float64
+	FirstChoiceCount     int     `json:"first_choice_count" yaml:"first_choice_count"`
+	Top3Count            int     `json:"top3_count" yaml:"top3_count"`
+	FirstChoiceHitRate   float64 `json:"first_choice_hit_rate" yaml:"first_choice_hit_rate"`
+	Top3HitRate          float64 `json:"top3_hit_rate" yaml:"top3_hit_rate"`
+	AverageRank          float64 `json:"average_rank" yaml:"average_rank"`
+	OverallAccuracyScore float64 `json:"overall_accuracy_score" yaml:"overall_accuracy_score"`
+	DirectInputRate      float64 `json:"direct_input_rate" yaml:"direct_input_rate"`
 
 	// Validation flags
-	HasValidSelections bool
-	HasCommits         bool
+	HasValidSelections bool `json:"has_valid_selections" yaml:"has_valid_selections"`
+	HasCommits         bool `json:"has_commits" yaml:"has_commits"`
 }
 
-// ReadLogFile parses a JSONL log file into a slice of LogEvent structs.
-// This matches the Python version: pd.read_json(lines=True)
-func ReadLogFile(filePath string) ([]LogEvent, error) {
-	file, err := os.Open(filePath)
+// ReadEvents parses every entry of the JSONL log files into typed events,
+// preserving the order in which the Lua logger wrote them. Pass a rotated
+// log's archives first and the live log last (see ScanLogs).
+// Prefer ScanLogs for large logs; this loads everything into memory.
+func ReadEvents(filePaths ...string) ([]Event, error) {
+	var events []Event
+	err := ScanLogs(filePaths, ScanOptions{}, func(event Event) error {
+		events = append(events, event)
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("could not open log file %s: %w", filePath, err)
+		return nil, err
 	}
-	defer file.Close()
+	return events, nil
+}
 
-	var events []LogEvent
-	scanner := bufio.NewScanner(file)
-	lineNumber := 0
+// ReadLogFile parses JSONL log files, plain or gzip-compressed, and returns
+// only their text_committed events, in the order given.
+// This matches the Python version: pd.read_json(lines=True)
+func ReadLogFile(filePaths ...string) ([]TextCommittedEvent, error) {
+	var commits []TextCommittedEvent
+	err := ScanCommits(filePaths, ScanOptions{}, func(commit *TextCommittedEvent) error {
+		commits = append(commits, *commit)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return commits, nil
+}
 
-	for scanner.Scan() {
-		lineNumber++
-		line := scanner.Text()
-		if line == "" {
-			continue
+// CommittedEvents filters a typed event list down to its text_committed entries.
+func CommittedEvents(events []Event) []TextCommittedEvent {
+	var commits []TextCommittedEvent
+	for _, event := range events {
+		if commit, ok := event.(*TextCommittedEvent); ok {
+			commits = append(commits, *commit)
 		}
+	}
+	return commits
+}
 
-		var event LogEvent
-		if err := json.Unmarshal([]byte(line), &event); err != nil {
-			// Skip invalid JSON lines but continue processing
-			fmt.Printf("Warning: Skipping invalid JSON on line %d: %v\n", lineNumber, err)
-			continue
-		}
+// Accumulator computes an AnalysisResult incrementally, one commit at a time,
+// so that arbitrarily large logs can be analyzed in constant memory.
+type Accumulator struct {
+	totalCommits    int
+	totalSelections int
+	rawInputCommits int
+	firstChoice     int
+	top3            int
+	totalRank       int
+	accuracySum     float64
+}
 
-		// Only include text_committed events for analysis
-		if event.EventType == "text_committed" {
-			events = append(events, event)
-		}
+// NewAccumulator returns an empty Accumulator.
+func NewAccumulator() *Accumulator {
+	return &Accumulator{}
+}
+
+// Add records a single text_committed event.
+func (a *Accumulator) Add(event *TextCommittedEvent) {
+	// df_commit = df[df['event_type'] == 'text_committed'].copy()
+	a.totalCommits++
+
+	if event.SelectedCandidateRank == nil {
+		// This shouldn't happen for text_committed events, but handle gracefully
+		return
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading log file: %w", err)
+	rank := *event.SelectedCandidateRank
+	if rank == -1 {
+		// Direct input (raw text, no candidate selection)
+		a.rawInputCommits++
+		return
+	}
+	if rank < 0 {
+		return
 	}
 
-	return events, nil
+	// df_selections = df_commit[df_commit['selected_candidate_rank'] >= 0].copy()
+	a.totalSelections++
+	a.totalRank += rank
+
+	// first_choice_count = (df_selections['selected_candidate_rank'] == 0).sum()
+	if rank == 0 {
+		a.firstChoice++
+	}
+
+	// top_3_count = (df_selections['selected_candidate_rank'] < 3).sum()
+	if rank < 3 {
+		a.top3++
+	}
+
+	// df_selections['accuracy_score'] = 1 / (df_selections['selected_candidate_rank'] + 1)
+	a.accuracySum += 1.0 / float64(rank+1)
 }
 
-// PerformAnalysis calculates comprehensive metrics from a list of log events.
+// Result returns the metrics for every commit added so far.
 // This exactly matches the Python version's analyze() method logic.
-func PerformAnalysis(events []LogEvent) AnalysisResult {
+func (a *Accumulator) Result() AnalysisResult {
 	var result AnalysisResult
 
-	// df_commit = df[df['event_type'] == 'text_committed'].copy()
-	// This filtering is already done in ReadLogFile, so events are all text_committed
-
-	result.TotalCommits = len(events)
+	result.TotalCommits = a.totalCommits
 	result.HasCommits = result.TotalCommits > 0
 
 	if !result.HasCommits {
 		return result
 	}
 
-	// df_selections = df_commit[df_commit['selected_candidate_rank'] >= 0].copy()
-	var validSelections []LogEvent
-	var rawInputCount int
-
-	for _, event := range events {
-		if event.SelectedCandidateRank == nil {
-			// This shouldn't happen for text_committed events, but handle gracefully
-			continue
-		}
-
-		rank := *event.SelectedCandidateRank
-
-		if rank >= 0 {
-			// Valid candidate selection
-			validSelections = append(validSelections, event)
-		} else if rank == -1 {
-			// Direct input (raw text, no candidate selection)
-			rawInputCount++
-		}
-	}
-
-	result.TotalSelections = len(validSelections)
-	result.RawInputCommits = rawInputCount
+	result.TotalSelections = a.totalSelections
+	result.RawInputCommits = a.rawInputCommits
 	result.HasValidSelections = result.TotalSelections > 0
 
 	// Calculate direct input rate
 	// raw_input_commits / total_commits
-	if result.TotalCommits > 0 {
-		result.DirectInputRate = (float64(result.RawInputCommits) / float64(result.TotalCommits)) * 100
-	}
+	result.DirectInputRate = (float64(result.RawInputCommits) / float64(result.TotalCommits)) * 100
 
 	if !result.HasValidSelections {
 		return result
 	}
 
-	// Calculate accuracy metrics for valid selections
-	var totalRank int
-	var accuracySum float64
-
-	for _, event := range validSelections {
-		rank := *event.SelectedCandidateRank
-		totalRank += rank
-
-		// first_choice_count = (df_selections['selected_candidate_rank'] == 0).sum()
-		if rank == 0 {
-			result.FirstChoiceCount++
-		}
-
-		// top_3_count = (df_selections['selected_candidate_rank'] < 3).sum()
-		if rank < 3 {
-			result.Top3Count++
-		}
-
-		// df_selections['accuracy_score'] = 1 / (df_selections['selected_candidate_rank'] + 1)
-		accuracySum += 1.0 / float64(rank+1)
-	}
+	result.FirstChoiceCount = a.firstChoice
+	result.Top3Count = a.top3
 
 	// Calculate rates and averages
 	totalSelectionsFloat := float64(result.TotalSelections)
@@ -168,17 +165,70 @@ func PerformAnalysis(events []LogEvent) AnalysisResult {
 	result.Top3HitRate = (float64(result.Top3Count) / totalSelectionsFloat) * 100
 
 	// df_selections['selected_candidate_rank'].mean()
-	result.AverageRank = float64(totalRank) / totalSelectionsFloat
+	result.AverageRank = float64(a.totalRank) / totalSelectionsFloat
 
 	// overall_accuracy_score = df_selections['accuracy_score'].mean()
-	result.OverallAccuracyScore = accuracySum / totalSelectionsFloat
+	result.OverallAccuracyScore = a.accuracySum / totalSelectionsFloat
 
 	return result
 }
 
-// ExportMisses filters for mispredictions (rank > 0) and writes them to a CSV file.
+// PerformAnalysis calculates comprehensive metrics from a list of log events.
+func PerformAnalysis(events []TextCommittedEvent) AnalysisResult {
+	acc := NewAccumulator()
+	for i := range events {
+		acc.Add(&events[i])
+	}
+	return acc.Result()
+}
+
+// missRecord is a single misprediction row of the CSV report.
+type missRecord struct {
+	UserInput    string
+	ActualChoice string
+	ProgramPred  string
+	SelectedRank int
+}
+
+// MissCollector gathers mispredictions (rank > 0) from a stream of commits.
+// Only the misses themselves are retained, not every commit.
+type MissCollector struct {
+	misses        []missRecord
+	missFrequency map[string]int
+	totalCommits  int
+}
+
+// NewMissCollector returns an empty MissCollector.
+func NewMissCollector() *MissCollector {
+	return &MissCollector{missFrequency: make(map[string]int)}
+}
+
+// Add records the commit if it is a misprediction.
+func (c *MissCollector) Add(event *TextCommittedEvent) {
+	c.totalCommits++
+
+	// df_misses = df_commit[df_commit['selected_candidate_rank'] > 0].copy()
+	if event.SelectedCandidateRank == nil || *event.SelectedCandidateRank <= 0 {
+		return
+	}
+	c.misses = append(c.misses, missRecord{
+		UserInput:    event.SourceInputBuffer,
+		ActualChoice: event.CommittedText,
+		ProgramPred:  event.SourceFirstCandidate,
+		SelectedRank: *event.SelectedCandidateRank,
+	})
+	c.missFrequency[event.CommittedText]++
+}
+
+// TotalCommits returns the number of commits seen by the collector.
+func (c *MissCollector) TotalCommits() int { return c.totalCommits }
+
+// MissCount returns the number of mispredictions collected.
+func (c *MissCollector) MissCount() int { return len(c.misses) }
+
+// WriteCSV writes the collected mispredictions to a CSV file.
 // The output format matches the Python version's CSV structure.
-func ExportMisses(events []LogEvent, outputCsvPath string) error {
+func (c *MissCollector) WriteCSV(outputCsvPath string) error {
 	// If no output path specified, use default like Python version
 	if outputCsvPath == "" {
 		homeDir, err := os.UserHomeDir()
@@ -204,51 +254,18 @@ func ExportMisses(events []LogEvent, outputCsvPath string) error {
 		return fmt.Errorf("failed to write CSV header: %w", err)
 	}
 
-	// Collect mispredictions for sorting (matching Python logic)
-	type MissRecord struct {
-		UserInput    string
-		ActualChoice string
-		ProgramPred  string
-		SelectedRank int
-		Frequency    int
-	}
-
-	var misses []MissRecord
-	missFrequency := make(map[string]int)
-
-	// First pass: collect misses and count frequency
-	// df_misses = df_commit[df_commit['selected_candidate_rank'] > 0].copy()
-	for _, event := range events {
-		if event.SelectedCandidateRank != nil && *event.SelectedCandidateRank > 0 {
-			miss := MissRecord{
-				UserInput:    event.SourceInputBuffer,
-				ActualChoice: event.CommittedText,
-				ProgramPred:  event.SourceFirstCandidate,
-				SelectedRank: *event.SelectedCandidateRank,
-			}
-			misses = append(misses, miss)
-			missFrequency[event.CommittedText]++
-		}
-	}
-
-	// Second pass: add frequency information
-	for i := range misses {
-		misses[i].Frequency = missFrequency[misses[i].ActualChoice]
-	}
-
 	// Sort by frequency (descending) then by user input (ascending)
 	// Python: sort_values(by=['错误频率', '用户输入'], ascending=[False, True])
-	for i := 0; i < len(misses)-1; i++ {
-		for j := 0; j < len(misses)-i-1; j++ {
-			if misses[j].Frequency < misses[j+1].Frequency ||
-				(misses[j].Frequency == misses[j+1].Frequency && misses[j].UserInput > misses[j+1].UserInput) {
-				misses[j], misses[j+1] = misses[j+1], misses[j]
-			}
+	sort.SliceStable(c.misses, func(i, j int) bool {
+		fi, fj := c.missFrequency[c.misses[i].ActualChoice], c.missFrequency[c.misses[j].ActualChoice]
+		if fi != fj {
+			return fi > fj
 		}
-	}
+		return c.misses[i].UserInput < c.misses[j].UserInput
+	})
 
 	// Write data rows
-	for _, miss := range misses {
+	for _, miss := range c.misses {
 		row := []string{
 			miss.UserInput,
 			miss.ActualChoice,
@@ -263,8 +280,17 @@ func ExportMisses(events []LogEvent, outputCsvPath string) error {
 	return nil
 }
 
+// ExportMisses filters for mispredictions (rank > 0) and writes them to a CSV file.
+func ExportMisses(events []TextCommittedEvent, outputCsvPath string) error {
+	collector := NewMissCollector()
+	for i := range events {
+		collector.Add(&events[i])
+	}
+	return collector.WriteCSV(outputCsvPath)
+}
+
 // GetMissCount returns the number of mispredictions in the events.
-func GetMissCount(events []LogEvent) int {
+func GetMissCount(events []TextCommittedEvent) int {
 	count := 0
 	for _, event := range events {
 		if event.SelectedCandidateRank != nil && *event.SelectedCandidateRank > 0 {
@@ -0,0 +1,41 @@
+package analyzer
+
+import "sort"
+
+// DeviceResult holds the metrics for the commits of one device.
+type DeviceResult struct {
+	// Device is the device_id of a merged log, or "" for untagged entries.
+	Device string         `json:"device" yaml:"device"`
+	Result AnalysisResult `json:"result" yaml:"result"`
+}
+
+// DeviceTracker accumulates commits per device_id, as tagged by MergeLogs.
+type DeviceTracker struct {
+	devices map[string]*Accumulator
+}
+
+// NewDeviceTracker returns an empty DeviceTracker.
+func NewDeviceTracker() *DeviceTracker {
+	return &DeviceTracker{devices: make(map[string]*Accumulator)}
+}
+
+// Add records a single text_committed event under its device.
+func (t *DeviceTracker) Add(event *TextCommittedEvent) {
+	acc, ok := t.devices[event.DeviceID]
+	if !ok {
+		acc = NewAccumulator()
+		t.devices[event.DeviceID] = acc
+	}
+	acc.Add(event)
+}
+
+// Devices returns one result per device, sorted by device_id with
+// untagged entries first.
+func (t *DeviceTracker) Devices() []DeviceResult {
+	results := make([]DeviceResult, 0, len(t.devices))
+	for device, acc := range t.devices {
+		results = append(results, DeviceResult{Device: device, Result: acc.Result()})
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Device < results[j].Device })
+	return results
+}
@@ -0,0 +1,118 @@
+package analyzer
+
+import "sort"
+
+// PageSize is the number of candidates per menu page assumed by the Lua
+// logger when it converts a page-local choice into selected_candidate_rank.
+const PageSize = 6
+
+// SelectionMethodUnknown labels commits whose selection_method field was
+// not written, e.g. because the active preset disables it.
+const SelectionMethodUnknown = "unknown"
+
+// RankCount is the number of selections made at one candidate rank.
+type RankCount struct {
+	Rank    int     `json:"rank" yaml:"rank"`
+	Count   int     `json:"count" yaml:"count"`
+	Percent float64 `json:"percent" yaml:"percent"`
+}
+
+// PageCount is the number of selections made on one candidate menu page.
+type PageCount struct {
+	Page      int     `json:"page" yaml:"page"`
+	FirstRank int     `json:"first_rank" yaml:"first_rank"`
+	LastRank  int     `json:"last_rank" yaml:"last_rank"`
+	Count     int     `json:"count" yaml:"count"`
+	Percent   float64 `json:"percent" yaml:"percent"`
+}
+
+// MethodCount is the number of commits made with one selection_method.
+type MethodCount struct {
+	Method  string  `json:"method" yaml:"method"`
+	Count   int     `json:"count" yaml:"count"`
+	Percent float64 `json:"percent" yaml:"percent"`
+}
+
+// Distribution describes how selections spread over ranks, pages and selection methods.
+// Rank and page percentages are relative to candidate selections (rank >= 0);
+// selection method percentages are relative to all commits.
+type Distribution struct {
+	Ranks            []RankCount   `json:"ranks" yaml:"ranks"`
+	Pages            []PageCount   `json:"pages" yaml:"pages"`
+	SelectionMethods []MethodCount `json:"selection_methods" yaml:"selection_methods"`
+}
+
+// DistributionTracker accumulates rank and selection method counts from a stream of commits.
+type DistributionTracker struct {
+	ranks      map[int]int
+	methods    map[string]int
+	selections int
+	commits    int
+}
+
+// NewDistributionTracker returns an empty DistributionTracker.
+func NewDistributionTracker() *DistributionTracker {
+	return &DistributionTracker{
+		ranks:   make(map[int]int),
+		methods: make(map[string]int),
+	}
+}
+
+// Add records a single text_committed event.
+func (t *DistributionTracker) Add(event *TextCommittedEvent) {
+	t.commits++
+
+	method := event.SelectionMethod
+	if method == "" {
+		method = SelectionMethodUnknown
+	}
+	t.methods[method]++
+
+	if event.SelectedCandidateRank != nil && *event.SelectedCandidateRank >= 0 {
+		t.ranks[*event.SelectedCandidateRank]++
+		t.selections++
+	}
+}
+
+// Result returns the distribution of every commit added so far.
+// Ranks and pages are listed in ascending order, selection methods by descending count.
+func (t *DistributionTracker) Result() Distribution {
+	var d Distribution
+
+	pages := make(map[int]int)
+	for rank, count := range t.ranks {
+		d.Ranks = append(d.Ranks, RankCount{Rank: rank, Count: count, Percent: percent(count, t.selections)})
+		pages[rank/PageSize] += count
+	}
+	sort.Slice(d.Ranks, func(i, j int) bool { return d.Ranks[i].Rank < d.Ranks[j].Rank })
+
+	for page, count := range pages {
+		d.Pages = append(d.Pages, PageCount{
+			Page:      page + 1,
+			FirstRank: page * PageSize,
+			LastRank:  page*PageSize + PageSize - 1,
+			Count:     count,
+			Percent:   percent(count, t.selections),
+		})
+	}
+	sort.Slice(d.Pages, func(i, j int) bool { return d.Pages[i].Page < d.Pages[j].Page })
+
+	for method, count := range t.methods {
+		d.SelectionMethods = append(d.SelectionMethods, MethodCount{Method: method, Count: count, Percent: percent(count, t.commits)})
+	}
+	sort.Slice(d.SelectionMethods, func(i, j int) bool {
+		if d.SelectionMethods[i].Count != d.SelectionMethods[j].Count {
+			return d.SelectionMethods[i].Count > d.SelectionMethods[j].Count
+		}
+		return d.SelectionMethods[i].Method < d.SelectionMethods[j].Method
+	})
+
+	return d
+}
+
+func percent(count, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(count) / float64(total) * 100
+}
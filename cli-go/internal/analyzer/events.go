@@ -0,0 +1,143 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Event type names written by input_habit_logger.lua into the "event_type" field.
+const (
+	EventSessionStart      = "session_start"
+	EventSessionEnd        = "session_end"
+	EventTextCommitted     = "text_committed"
+	EventInputStateChanged = "input_state_changed"
+	EventError             = "error"
+)
+
+// Event is implemented by every typed log entry. The concrete type can be
+// recovered with a type switch on *SessionStartEvent, *TextCommittedEvent, etc.
+type Event interface {
+	// Kind returns the raw "event_type" value of the entry.
+	Kind() string
+	// Time returns the parsed "timestamp" value, or the zero time if it is missing or malformed.
+	Time() time.Time
+	// Device returns the "device_id" added by MergeLogs, or "" for an unmerged log.
+	Device() string
+}
+
+// BaseEvent holds the fields shared by every entry the Lua logger writes.
+type BaseEvent struct {
+	EventType string `json:"event_type"`
+	Timestamp string `json:"timestamp,omitempty"`
+	// DeviceID is added by MergeLogs; the logger itself never writes it.
+	DeviceID string `json:"device_id,omitempty"`
+}
+
+// Kind implements Event.
+func (e *BaseEvent) Kind() string { return e.EventType }
+
+// Time implements Event.
+func (e *BaseEvent) Time() time.Time {
+	return parseTimestamp(e.Timestamp)
+}
+
+// Device implements Event.
+func (e *BaseEvent) Device() string { return e.DeviceID }
+
+// SessionStartEvent is written when the logger is initialised for a schema.
+type SessionStartEvent struct {
+	BaseEvent
+	SchemaID string `json:"schema_id,omitempty"`
+}
+
+// SessionEndEvent is written when the logger is torn down (fini).
+type SessionEndEvent struct {
+	BaseEvent
+}
+
+// TextCommittedEvent is written every time text is committed to the application.
+// Fields are tagged for JSON unmarshalling and match the Lua script output.
+type TextCommittedEvent struct {
+	BaseEvent
+	SelectedCandidateRank *int     `json:"selected_candidate_rank,omitempty"`
+	CommittedText         string   `json:"committed_text,omitempty"`
+	SourceFirstCandidate  string   `json:"source_first_candidate,omitempty"`
+	InputSequenceAtCommit string   `json:"input_sequence_at_commit,omitempty"`
+	SourceCandidatesList  []string `json:"source_candidates_list,omitempty"`
+	SourceInputBuffer     string   `json:"source_input_buffer,omitempty"`
+	SelectionMethod       string   `json:"selection_method,omitempty"`
+	SourceEventTimestamp  string   `json:"source_event_timestamp,omitempty"`
+}
+
+// InputStateChangedEvent is written on key presses that change the candidate menu.
+type InputStateChangedEvent struct {
+	BaseEvent
+	EventSubtype   string   `json:"event_subtype,omitempty"`
+	KeyAction      string   `json:"key_action,omitempty"`
+	InputBuffer    string   `json:"input_buffer,omitempty"`
+	Candidates     []string `json:"candidates,omitempty"`
+	FirstCandidate string   `json:"first_candidate,omitempty"`
+	HasMenu        *bool    `json:"has_menu,omitempty"`
+}
+
+// ErrorEvent is written when the Lua processor catches an error.
+type ErrorEvent struct {
+	BaseEvent
+	Component string `json:"component,omitempty"`
+	Message   string `json:"message,omitempty"`
+	KeyRepr   string `json:"key_repr,omitempty"`
+}
+
+// UnknownEvent preserves entries whose event_type this version does not recognise,
+// so readers can still report them in order instead of silently dropping them.
+type UnknownEvent struct {
+	BaseEvent
+	Raw json.RawMessage `json:"-"`
+}
+
+// ParseEvent decodes a single JSONL line into its typed Event.
+func ParseEvent(line []byte) (Event, error) {
+	var base BaseEvent
+	if err := json.Unmarshal(line, &base); err != nil {
+		return nil, err
+	}
+
+	var event Event
+	switch base.EventType {
+	case EventSessionStart:
+		event = &SessionStartEvent{}
+	case EventSessionEnd:
+		event = &SessionEndEvent{}
+	case EventTextCommitted:
+		event = &TextCommittedEvent{}
+	case EventInputStateChanged:
+		event = &InputStateChangedEvent{}
+	case EventError:
+		event = &ErrorEvent{}
+	case "":
+		return nil, fmt.Errorf("missing event_type")
+	default:
+		raw := make(json.RawMessage, len(line))
+		copy(raw, line)
+		return &UnknownEvent{BaseEvent: base, Raw: raw}, nil
+	}
+
+	if err := json.Unmarshal(line, event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// parseTimestamp parses the ISO-8601 UTC timestamps produced by the Lua logger
+// (e.g. "2024-05-01T08:30:00.123Z").
+func parseTimestamp(value string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
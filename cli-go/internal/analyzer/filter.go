@@ -0,0 +1,150 @@
+package analyzer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Filter narrows the events an analysis sees. The zero value matches everything.
+//
+// Since, Until and SchemaID apply to every event type so that session
+// boundaries stay intact; the remaining fields only restrict text_committed events.
+type Filter struct {
+	// Since and Until bound the event timestamp; Until is exclusive. Zero means unbounded.
+	Since time.Time
+	Until time.Time
+	// SchemaID keeps only events logged inside a session started for this schema.
+	SchemaID string
+	// SelectionMethod keeps commits whose selection_method starts with this value,
+	// so "nth_choice_number" matches "nth_choice_number_2".
+	SelectionMethod string
+	// MinRank keeps commits whose selected_candidate_rank is at least this value.
+	MinRank *int
+	// InputPrefix keeps commits whose input code starts with this value.
+	InputPrefix string
+}
+
+// IsZero reports whether the filter matches every event.
+func (f Filter) IsZero() bool {
+	return f.Since.IsZero() && f.Until.IsZero() && f.SchemaID == "" &&
+		f.SelectionMethod == "" && f.MinRank == nil && f.InputPrefix == ""
+}
+
+// Matcher returns a predicate for a single pass over a log, in file order.
+// It is stateful because the schema of an event is only known from the
+// session_start that precedes it on the same device; the sessions of a
+// merged log are interleaved.
+func (f Filter) Matcher() func(Event) bool {
+	currentSchema := make(map[string]string) // by device_id
+	return func(event Event) bool {
+		device := event.Device()
+		if start, ok := event.(*SessionStartEvent); ok {
+			currentSchema[device] = start.SchemaID
+		}
+		schema := currentSchema[device]
+		if _, ok := event.(*SessionEndEvent); ok {
+			delete(currentSchema, device)
+		}
+
+		if f.SchemaID != "" && schema != f.SchemaID {
+			return false
+		}
+		if !f.Since.IsZero() || !f.Until.IsZero() {
+			ts := event.Time()
+			if ts.IsZero() {
+				return false
+			}
+			if !f.Since.IsZero() && ts.Before(f.Since) {
+				return false
+			}
+			if !f.Until.IsZero() && !ts.Before(f.Until) {
+				return false
+			}
+		}
+
+		commit, ok := event.(*TextCommittedEvent)
+		if !ok {
+			return true
+		}
+		return f.matchCommit(commit)
+	}
+}
+
+func (f Filter) matchCommit(commit *TextCommittedEvent) bool {
+	if f.SelectionMethod != "" && !strings.HasPrefix(commit.SelectionMethod, f.SelectionMethod) {
+		return false
+	}
+	if f.MinRank != nil {
+		if commit.SelectedCandidateRank == nil || *commit.SelectedCandidateRank < *f.MinRank {
+			return false
+		}
+	}
+	if f.InputPrefix != "" && !strings.HasPrefix(commit.InputCode(), f.InputPrefix) {
+		return false
+	}
+	return true
+}
+
+// InputCode returns the raw input that produced the commit, preferring the
+// source input buffer and falling back to the input sequence at commit time.
+func (e *TextCommittedEvent) InputCode() string {
+	if e.SourceInputBuffer != "" && e.SourceInputBuffer != "N/A" {
+		return e.SourceInputBuffer
+	}
+	if e.InputSequenceAtCommit != "N/A" {
+		return e.InputSequenceAtCommit
+	}
+	return ""
+}
+
+// ParseTimeBound parses a --since/--until value relative to now. It accepts
+// RFC 3339 timestamps, local dates ("2024-05-01"), local date-times
+// ("2024-05-01 08:30") and relative ages such as "90m", "12h", "7d" or "2w".
+// When endOfDay is set, a bare date resolves to the start of the following
+// day so that it can be used as an exclusive upper bound covering that date.
+func ParseTimeBound(value string, now time.Time, endOfDay bool) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04", value, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		if endOfDay {
+			return t.AddDate(0, 0, 1), nil
+		}
+		return t, nil
+	}
+	if age, err := ParseAge(value); err == nil {
+		return now.Add(-age), nil
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time %q (expected YYYY-MM-DD, \"YYYY-MM-DD HH:MM\", RFC 3339 or an age like 7d)", value)
+}
+
+// ParseAge extends time.ParseDuration with day ("d") and week ("w") units.
+func ParseAge(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, fmt.Errorf("empty age")
+	}
+	unit := value[len(value)-1]
+	if unit == 'd' || unit == 'w' {
+		n, err := strconv.Atoi(value[:len(value)-1])
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age %q", value)
+		}
+		days := n
+		if unit == 'w' {
+			days *= 7
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(value)
+}
@@ -0,0 +1,267 @@
+package analyzer
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// DeviceField is the field merged logs use to record which device wrote an entry.
+const DeviceField = "device_id"
+
+// MergeSource is the log of one device: its files, read in order (archives
+// first, then the live log).
+type MergeSource struct {
+	Device string   `json:"device" yaml:"device"`
+	Paths  []string `json:"paths" yaml:"paths"`
+}
+
+// MergeOptions controls how logs are read by MergeLogs.
+type MergeOptions struct {
+	// MaxLineSize is the maximum size in bytes of a single line. Zero means DefaultMaxLineSize.
+	MaxLineSize int
+	// OnInvalidLine is called for lines that are not JSON objects. When nil they are skipped silently.
+	OnInvalidLine func(path string, lineNumber int, err error)
+}
+
+// MergeStats counts what MergeLogs did.
+type MergeStats struct {
+	Read       int `json:"read" yaml:"read"`
+	Written    int `json:"written" yaml:"written"`
+	Duplicates int `json:"duplicates" yaml:"duplicates"`
+	Invalid    int `json:"invalid" yaml:"invalid"`
+}
+
+// MergeLogs writes the entries of every source to w as one JSONL stream in
+// timestamp order. Entries without a device_id are tagged with their
+// source's Device; entries that already have one (e.g. from an earlier
+// merge) keep it. Exact duplicates, compared without device_id as sync tools
+// may have copied the same log into several sources, are written once.
+//
+// Entries are ordered by the second of their timestamp only: the logger
+// takes the milliseconds from os.clock(), so they do not increase within a
+// source. Each source is assumed to be in order of those seconds already, as
+// the logger writes it, so only one entry per source is held in memory, and
+// entries of the same second keep their order within their source.
+// Duplicates are only looked for among the entries of the same second. Entries without a
+// timestamp stay right after the entry preceding them in their source.
+func MergeLogs(sources []MergeSource, w io.Writer, opts MergeOptions) (MergeStats, error) {
+	var stats MergeStats
+	readers := make([]*mergeReader, 0, len(sources))
+	defer func() {
+		for _, r := range readers {
+			r.close()
+		}
+	}()
+
+	queue := &mergeQueue{}
+	for i, source := range sources {
+		r := &mergeReader{index: i, source: source, opts: opts, stats: &stats}
+		readers = append(readers, r)
+		if err := r.advance(); err != nil {
+			return stats, err
+		}
+		if r.entry != nil {
+			heap.Push(queue, r)
+		}
+	}
+
+	out := bufio.NewWriter(w)
+	// Duplicates have the same timestamp, so they fall in the same second and
+	// leave the queue in the same run; only the keys of that second are kept.
+	seen := make(map[[sha256.Size]byte]struct{})
+	var seenSecond int64
+	for queue.Len() > 0 {
+		r := (*queue)[0]
+		entry := r.entry
+		if entry.time != seenSecond {
+			clear(seen)
+			seenSecond = entry.time
+		}
+		if _, dup := seen[entry.key]; dup {
+			stats.Duplicates++
+		} else {
+			seen[entry.key] = struct{}{}
+			if _, err := out.Write(entry.line); err != nil {
+				return stats, err
+			}
+			if err := out.WriteByte('\n'); err != nil {
+				return stats, err
+			}
+			stats.Written++
+		}
+
+		if err := r.advance(); err != nil {
+			return stats, err
+		}
+		if r.entry == nil {
+			heap.Pop(queue)
+		} else {
+			heap.Fix(queue, 0)
+		}
+	}
+	return stats, out.Flush()
+}
+
+// mergeEntry is one tagged log line waiting to be written.
+type mergeEntry struct {
+	line []byte
+	time int64 // Unix second of the entry, or of the last timed entry before it
+	key  [sha256.Size]byte
+}
+
+// mergeReader streams the entries of one MergeSource, file after file.
+type mergeReader struct {
+	index  int
+	source MergeSource
+	opts   MergeOptions
+	stats  *MergeStats
+
+	file       io.ReadCloser
+	scanner    *bufio.Scanner
+	next       int // index of the next file in source.Paths
+	path       string
+	lineNumber int
+	lastTime   int64
+	entry      *mergeEntry
+}
+
+// advance reads the next entry into r.entry, or sets it to nil at the end.
+func (r *mergeReader) advance() error {
+	r.entry = nil
+	for {
+		if r.scanner == nil {
+			if r.next >= len(r.source.Paths) {
+				return nil
+			}
+			if err := r.open(r.source.Paths[r.next]); err != nil {
+				return err
+			}
+			r.next++
+		}
+
+		if !r.scanner.Scan() {
+			err := r.scanner.Err()
+			if errors.Is(err, bufio.ErrTooLong) {
+				return fmt.Errorf("%s: line %d exceeds the maximum line size: %w", r.path, r.lineNumber+1, err)
+			}
+			if err != nil {
+				return fmt.Errorf("error reading log file %s: %w", r.path, err)
+			}
+			r.close()
+			continue
+		}
+
+		r.lineNumber++
+		line := bytes.TrimSpace(r.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		r.stats.Read++
+
+		entry, err := r.parse(line)
+		if err != nil {
+			r.stats.Invalid++
+			if r.opts.OnInvalidLine != nil {
+				r.opts.OnInvalidLine(r.path, r.lineNumber, err)
+			}
+			continue
+		}
+		r.entry = entry
+		return nil
+	}
+}
+
+// parse tags a line with the source's device and computes its duplicate key.
+func (r *mergeReader) parse(line []byte) (*mergeEntry, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(line, &fields); err != nil {
+		return nil, err
+	}
+	if fields == nil {
+		return nil, errors.New("not a JSON object")
+	}
+
+	var base BaseEvent
+	json.Unmarshal(line, &base)
+	if t := parseTimestamp(base.Timestamp); !t.IsZero() {
+		r.lastTime = t.Unix()
+	}
+
+	tagged := line
+	if _, ok := fields[DeviceField]; !ok && r.source.Device != "" {
+		device, _ := json.Marshal(r.source.Device)
+		tagged = make([]byte, 0, len(line)+len(DeviceField)+len(device)+4)
+		tagged = append(tagged, line[:len(line)-1]...)
+		if len(fields) > 0 {
+			tagged = append(tagged, ',')
+		}
+		tagged = append(tagged, `"`+DeviceField+`":`...)
+		tagged = append(tagged, device...)
+		tagged = append(tagged, '}')
+	} else {
+		tagged = append([]byte(nil), line...)
+	}
+
+	// Compare entries by content, ignoring which device they were attributed to
+	delete(fields, DeviceField)
+	canonical, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	return &mergeEntry{line: tagged, time: r.lastTime, key: sha256.Sum256(canonical)}, nil
+}
+
+func (r *mergeReader) open(path string) error {
+	file, err := openLogFile(path)
+	if err != nil {
+		return err
+	}
+	maxLineSize := r.opts.MaxLineSize
+	if maxLineSize <= 0 {
+		maxLineSize = DefaultMaxLineSize
+	}
+	r.file = file
+	r.path = path
+	r.lineNumber = 0
+	r.scanner = bufio.NewScanner(file)
+	r.scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	return nil
+}
+
+func (r *mergeReader) close() {
+	if r.file != nil {
+		r.file.Close()
+	}
+	r.file = nil
+	r.scanner = nil
+}
+
+// mergeQueue is a min-heap of readers ordered by the second of their pending
+// entry, then by source order.
+type mergeQueue []*mergeReader
+
+func (q mergeQueue) Len() int { return len(q) }
+
+func (q mergeQueue) Less(i, j int) bool {
+	if q[i].entry.time != q[j].entry.time {
+		return q[i].entry.time < q[j].entry.time
+	}
+	return q[i].index < q[j].index
+}
+
+func (q mergeQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *mergeQueue) Push(x any) { *q = append(*q, x.(*mergeReader)) }
+
+func (q *mergeQueue) Pop() any {
+	old := *q
+	r := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return r
+}
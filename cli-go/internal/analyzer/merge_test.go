@@ -0,0 +1,150 @@
+package analyzer
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeLog writes lines as a JSONL file in dir and returns its path.
+func writeLog(t *testing.T, dir, name string, lines ...string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// commit is a text_committed line as the logger writes it.
+func commit(timestamp, text string) string {
+	return `{"event_type":"text_committed","timestamp":"` + timestamp + `","committed_text":"` + text + `"}`
+}
+
+// mergedEntries returns the committed_text and device_id of every merged line.
+func mergedEntries(t *testing.T, out []byte) (texts, devices []string) {
+	t.Helper()
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		var e struct {
+			CommittedText string `json:"committed_text"`
+			DeviceID      string `json:"device_id"`
+		}
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("invalid merged line %q: %v", line, err)
+		}
+		texts = append(texts, e.CommittedText)
+		devices = append(devices, e.DeviceID)
+	}
+	return texts, devices
+}
+
+func TestMergeLogs(t *testing.T) {
+	// The logger's milliseconds come from os.clock(), so they may go backwards
+	// within a second
+	outOfOrder := []string{
+		commit("2026-01-01T08:00:00.900Z", "a"),
+		commit("2026-01-01T08:00:00.100Z", "b"),
+		commit("2026-01-01T08:00:01.050Z", "c"),
+		commit("2026-01-01T08:00:01.020Z", "d"),
+	}
+
+	tests := []struct {
+		name           string
+		sources        func(dir string) []MergeSource
+		wantTexts      []string
+		wantDevices    []string
+		wantDuplicates int
+	}{
+		{
+			name: "log copied twice",
+			sources: func(dir string) []MergeSource {
+				return []MergeSource{
+					{Device: "x", Paths: []string{writeLog(t, dir, "x.jsonl", outOfOrder...)}},
+					{Device: "y", Paths: []string{writeLog(t, dir, "y.jsonl", outOfOrder...)}},
+				}
+			},
+			wantTexts:      []string{"a", "b", "c", "d"},
+			wantDevices:    []string{"x", "x", "x", "x"},
+			wantDuplicates: 4,
+		},
+		{
+			name: "copy already tagged by an earlier merge",
+			sources: func(dir string) []MergeSource {
+				tagged := strings.Replace(outOfOrder[1], `}`, `,"device_id":"x"}`, 1)
+				return []MergeSource{
+					{Device: "y", Paths: []string{writeLog(t, dir, "y.jsonl", tagged)}},
+					{Device: "x", Paths: []string{writeLog(t, dir, "x.jsonl", outOfOrder...)}},
+				}
+			},
+			wantTexts:      []string{"b", "a", "c", "d"},
+			wantDevices:    []string{"x", "x", "x", "x"},
+			wantDuplicates: 1,
+		},
+		{
+			name: "interleaved devices",
+			sources: func(dir string) []MergeSource {
+				return []MergeSource{
+					{Device: "x", Paths: []string{writeLog(t, dir, "x.jsonl", outOfOrder...)}},
+					{Device: "y", Paths: []string{writeLog(t, dir, "y.jsonl",
+						commit("2026-01-01T08:00:00.500Z", "e"),
+						commit("2026-01-01T08:00:02.000Z", "f"),
+					)}},
+				}
+			},
+			wantTexts:   []string{"a", "b", "e", "c", "d", "f"},
+			wantDevices: []string{"x", "x", "y", "x", "x", "y"},
+		},
+		{
+			name: "same text in different seconds",
+			sources: func(dir string) []MergeSource {
+				return []MergeSource{
+					{Device: "x", Paths: []string{writeLog(t, dir, "x.jsonl", commit("2026-01-01T08:00:00.100Z", "a"))}},
+					{Device: "y", Paths: []string{writeLog(t, dir, "y.jsonl", commit("2026-01-01T08:00:01.100Z", "a"))}},
+				}
+			},
+			wantTexts:   []string{"a", "a"},
+			wantDevices: []string{"x", "y"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			stats, err := MergeLogs(tt.sources(t.TempDir()), &out, MergeOptions{})
+			if err != nil {
+				t.Fatalf("MergeLogs: %v", err)
+			}
+			texts, devices := mergedEntries(t, out.Bytes())
+			if !slices.Equal(texts, tt.wantTexts) {
+				t.Errorf("texts = %v, want %v", texts, tt.wantTexts)
+			}
+			if !slices.Equal(devices, tt.wantDevices) {
+				t.Errorf("devices = %v, want %v", devices, tt.wantDevices)
+			}
+			if stats.Duplicates != tt.wantDuplicates || stats.Written != len(tt.wantTexts) {
+				t.Errorf("stats = %+v, want %d written and %d duplicates", stats, len(tt.wantTexts), tt.wantDuplicates)
+			}
+		})
+	}
+}
+
+func TestMergeLogsInvalidLines(t *testing.T) {
+	dir := t.TempDir()
+	path := writeLog(t, dir, "x.jsonl", commit("2026-01-01T08:00:00.100Z", "a"), "not json", "[1]")
+
+	var invalid []int
+	var out bytes.Buffer
+	stats, err := MergeLogs([]MergeSource{{Device: "x", Paths: []string{path}}}, &out, MergeOptions{
+		OnInvalidLine: func(_ string, lineNumber int, _ error) { invalid = append(invalid, lineNumber) },
+	})
+	if err != nil {
+		t.Fatalf("MergeLogs: %v", err)
+	}
+	if stats.Read != 3 || stats.Written != 1 || stats.Invalid != 2 || !slices.Equal(invalid, []int{2, 3}) {
+		t.Errorf("stats = %+v, invalid lines %v", stats, invalid)
+	}
+}
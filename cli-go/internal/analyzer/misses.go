@@ -0,0 +1,182 @@
+package analyzer
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// MissPair aggregates every misprediction with the same input code, predicted
+// first candidate and actually chosen text.
+type MissPair struct {
+	Input     string    `json:"input" yaml:"input"`
+	Predicted string    `json:"predicted" yaml:"predicted"`
+	Chosen    string    `json:"chosen" yaml:"chosen"`
+	Count     int       `json:"count" yaml:"count"`
+	MeanRank  float64   `json:"mean_rank" yaml:"mean_rank"`
+	FirstSeen time.Time `json:"first_seen,omitzero" yaml:"first_seen,omitempty"`
+	LastSeen  time.Time `json:"last_seen,omitzero" yaml:"last_seen,omitempty"`
+}
+
+type missKey struct {
+	input, predicted, chosen string
+}
+
+type missAggregate struct {
+	count     int
+	rankSum   int
+	firstSeen time.Time
+	lastSeen  time.Time
+}
+
+// MissAggregator groups mispredictions (rank > 0) into MissPairs.
+// Memory grows with the number of distinct pairs, not with the number of commits.
+type MissAggregator struct {
+	pairs        map[missKey]*missAggregate
+	totalCommits int
+	missCount    int
+}
+
+// NewMissAggregator returns an empty MissAggregator.
+func NewMissAggregator() *MissAggregator {
+	return &MissAggregator{pairs: make(map[missKey]*missAggregate)}
+}
+
+// Add records the commit if it is a misprediction.
+func (a *MissAggregator) Add(event *TextCommittedEvent) {
+	a.totalCommits++
+	if event.SelectedCandidateRank == nil || *event.SelectedCandidateRank <= 0 {
+		return
+	}
+	a.missCount++
+
+	key := missKey{input: event.InputCode(), predicted: event.PredictedText(), chosen: event.CommittedText}
+	agg, ok := a.pairs[key]
+	if !ok {
+		agg = &missAggregate{}
+		a.pairs[key] = agg
+	}
+	agg.count++
+	agg.rankSum += *event.SelectedCandidateRank
+
+	if ts := event.Time(); !ts.IsZero() {
+		if agg.firstSeen.IsZero() || ts.Before(agg.firstSeen) {
+			agg.firstSeen = ts
+		}
+		if ts.After(agg.lastSeen) {
+			agg.lastSeen = ts
+		}
+	}
+}
+
+// TotalCommits returns the number of commits seen by the aggregator.
+func (a *MissAggregator) TotalCommits() int { return a.totalCommits }
+
+// MissCount returns the number of individual mispredictions seen.
+func (a *MissAggregator) MissCount() int { return a.missCount }
+
+// PairCount returns the number of distinct misprediction pairs.
+func (a *MissAggregator) PairCount() int { return len(a.pairs) }
+
+// WriteCSV writes every aggregated pair to a CSV file, most frequent first.
+func (a *MissAggregator) WriteCSV(outputCsvPath string) error {
+	return WriteMissPairsCSV(a.Pairs(), outputCsvPath)
+}
+
+// Pairs returns every aggregated pair, most frequent first. Ties are broken
+// by the most recently seen pair, then by input code.
+func (a *MissAggregator) Pairs() []MissPair {
+	pairs := make([]MissPair, 0, len(a.pairs))
+	for key, agg := range a.pairs {
+		pairs = append(pairs, MissPair{
+			Input:     key.input,
+			Predicted: key.predicted,
+			Chosen:    key.chosen,
+			Count:     agg.count,
+			MeanRank:  float64(agg.rankSum) / float64(agg.count),
+			FirstSeen: agg.firstSeen,
+			LastSeen:  agg.lastSeen,
+		})
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].Count != pairs[j].Count {
+			return pairs[i].Count > pairs[j].Count
+		}
+		if !pairs[i].LastSeen.Equal(pairs[j].LastSeen) {
+			return pairs[i].LastSeen.After(pairs[j].LastSeen)
+		}
+		if pairs[i].Input != pairs[j].Input {
+			return pairs[i].Input < pairs[j].Input
+		}
+		return pairs[i].Chosen < pairs[j].Chosen
+	})
+	return pairs
+}
+
+// Top returns at most n pairs seen at least minCount times. n <= 0 means no limit.
+func (a *MissAggregator) Top(n, minCount int) []MissPair {
+	var top []MissPair
+	for _, p := range a.Pairs() {
+		if p.Count < minCount {
+			break
+		}
+		top = append(top, p)
+		if n > 0 && len(top) == n {
+			break
+		}
+	}
+	return top
+}
+
+// PredictedText returns the candidate Rime ranked first when the text was committed.
+func (e *TextCommittedEvent) PredictedText() string {
+	if e.SourceFirstCandidate != "" {
+		return e.SourceFirstCandidate
+	}
+	if len(e.SourceCandidatesList) > 0 {
+		return e.SourceCandidatesList[0]
+	}
+	return ""
+}
+
+// WriteMissPairsCSV writes aggregated mispredictions to a CSV file.
+func WriteMissPairsCSV(pairs []MissPair, outputCsvPath string) error {
+	file, err := os.Create(outputCsvPath)
+	if err != nil {
+		return fmt.Errorf("could not create CSV file %s: %w", outputCsvPath, err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	header := []string{"用户输入", "程序预测", "实际选择", "错误次数", "平均排名", "首次出现", "最近出现"}
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+	for _, p := range pairs {
+		row := []string{
+			p.Input,
+			p.Predicted,
+			p.Chosen,
+			strconv.Itoa(p.Count),
+			strconv.FormatFloat(p.MeanRank, 'f', 2, 64),
+			FormatCSVTime(p.FirstSeen),
+			FormatCSVTime(p.LastSeen),
+		}
+		if err := writer.Write(row); err != nil {
+			return fmt.Errorf("failed to write CSV row: %w", err)
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// FormatCSVTime formats t as RFC 3339, or as an empty cell when it is unknown.
+func FormatCSVTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,134 @@
+package analyzer
+
+import (
+	"sort"
+	"time"
+)
+
+// Session is one working session reconstructed from session_start/session_end boundaries.
+type Session struct {
+	Index    int       `json:"index" yaml:"index"`
+	SchemaID string    `json:"schema_id" yaml:"schema_id"`
+	Device   string    `json:"device,omitempty" yaml:"device,omitempty"` // device_id of a merged log
+	Start    time.Time `json:"start" yaml:"start"`
+	End      time.Time `json:"end" yaml:"end"`
+	// Ended is false when no session_end was seen, e.g. after a crash or when
+	// the next session_start arrived first. End is then the last event's time.
+	Ended bool `json:"ended" yaml:"ended"`
+	// Implicit is true when events were logged without a preceding session_start.
+	Implicit   bool           `json:"implicit" yaml:"implicit"`
+	ErrorCount int            `json:"error_count" yaml:"error_count"`
+	Result     AnalysisResult `json:"result" yaml:"result"`
+}
+
+// Duration returns the wall-clock length of the session.
+func (s Session) Duration() time.Duration {
+	if s.Start.IsZero() || s.End.IsZero() || s.End.Before(s.Start) {
+		return 0
+	}
+	return s.End.Sub(s.Start)
+}
+
+// SessionTracker splits a stream of events into sessions. The sessions of
+// each device_id are tracked separately, since a merged log interleaves them.
+type SessionTracker struct {
+	sessions []Session
+	open     map[string]*openSession // by device_id
+	opened   int
+}
+
+// openSession is a session that has not ended yet.
+type openSession struct {
+	session Session
+	acc     *Accumulator
+}
+
+// NewSessionTracker returns an empty SessionTracker.
+func NewSessionTracker() *SessionTracker {
+	return &SessionTracker{open: make(map[string]*openSession)}
+}
+
+// Add feeds the next event of the log, in file order.
+func (t *SessionTracker) Add(event Event) {
+	ts := event.Time()
+	device := event.Device()
+
+	switch e := event.(type) {
+	case *SessionStartEvent:
+		// A new start while a session is still open means the previous one never ended cleanly.
+		t.close(device, false)
+		t.start(device, ts, false).session.SchemaID = e.SchemaID
+		return
+	case *SessionEndEvent:
+		current, ok := t.open[device]
+		if !ok {
+			// An end without a start carries no data worth reporting.
+			return
+		}
+		current.touch(ts)
+		t.close(device, true)
+		return
+	}
+
+	current, ok := t.open[device]
+	if !ok {
+		current = t.start(device, ts, true)
+	}
+	current.touch(ts)
+
+	switch e := event.(type) {
+	case *TextCommittedEvent:
+		current.acc.Add(e)
+	case *ErrorEvent:
+		current.session.ErrorCount++
+	}
+}
+
+// Sessions closes any session still open and returns all sessions in the
+// order they started in the log.
+func (t *SessionTracker) Sessions() []Session {
+	for device := range t.open {
+		t.close(device, false)
+	}
+	sort.Slice(t.sessions, func(i, j int) bool { return t.sessions[i].Index < t.sessions[j].Index })
+	return t.sessions
+}
+
+func (t *SessionTracker) start(device string, ts time.Time, implicit bool) *openSession {
+	t.opened++
+	current := &openSession{
+		session: Session{
+			Index:    t.opened,
+			Device:   device,
+			Start:    ts,
+			End:      ts,
+			Implicit: implicit,
+		},
+		acc: NewAccumulator(),
+	}
+	t.open[device] = current
+	return current
+}
+
+func (s *openSession) touch(ts time.Time) {
+	if ts.IsZero() {
+		return
+	}
+	if s.session.Start.IsZero() {
+		s.session.Start = ts
+	}
+	if ts.After(s.session.End) {
+		s.session.End = ts
+	}
+}
+
+func (t *SessionTracker) close(device string, ended bool) {
+	current, ok := t.open[device]
+	if !ok {
+		return
+	}
+	current.session.Ended = ended
+	current.session.Result = current.acc.Result()
+	t.sessions = append(t.sessions, current.session)
+	delete(t.open, device)
+}
@@ -0,0 +1,164 @@
+package analyzer
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// DefaultMaxLineSize is the longest JSONL line accepted when ScanOptions.MaxLineSize is unset.
+// The advanced preset logs full candidate lists, which easily exceeds bufio's 64 KB default.
+const DefaultMaxLineSize = 16 * 1024 * 1024
+
+// ScanOptions controls how a JSONL log is streamed.
+type ScanOptions struct {
+	// MaxLineSize is the maximum size in bytes of a single line. Zero means DefaultMaxLineSize.
+	MaxLineSize int
+	// OnInvalidLine is called for lines that cannot be parsed. When nil, a warning is printed to stderr.
+	OnInvalidLine func(lineNumber int, err error)
+	// Filter drops events before they reach the callback.
+	Filter Filter
+
+	// match is the Filter's matcher when it is shared by several files, so
+	// that a session continuing into the next file keeps its schema.
+	match func(Event) bool
+}
+
+// ErrStopScan can be returned from a scan callback to end the scan early without an error.
+var ErrStopScan = errors.New("stop scan")
+
+// ScanEvents streams typed events from r, calling fn for each one in order.
+// Only one line is held in memory at a time.
+func ScanEvents(r io.Reader, opts ScanOptions, fn func(Event) error) error {
+	maxLineSize := opts.MaxLineSize
+	if maxLineSize <= 0 {
+		maxLineSize = DefaultMaxLineSize
+	}
+	onInvalid := opts.OnInvalidLine
+	if onInvalid == nil {
+		onInvalid = func(lineNumber int, err error) {
+			fmt.Fprintf(os.Stderr, "Warning: Skipping invalid JSON on line %d: %v\n", lineNumber, err)
+		}
+	}
+
+	match := opts.match
+	if match == nil && !opts.Filter.IsZero() {
+		match = opts.Filter.Matcher()
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	lineNumber := 0
+
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		event, err := ParseEvent(line)
+		if err != nil {
+			// Skip invalid JSON lines but continue processing
+			onInvalid(lineNumber, err)
+			continue
+		}
+		if match != nil && !match(event) {
+			continue
+		}
+
+		if err := fn(event); err != nil {
+			if errors.Is(err, ErrStopScan) {
+				return nil
+			}
+			return err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return fmt.Errorf("line %d exceeds the maximum line size of %d bytes: %w", lineNumber+1, maxLineSize, err)
+		}
+		return fmt.Errorf("error reading log file: %w", err)
+	}
+	return nil
+}
+
+// ScanLogFile opens a JSONL log file and streams its typed events to fn.
+// Files ending in .gz (the archives written by the rotate command) are
+// decompressed on the fly.
+func ScanLogFile(filePath string, opts ScanOptions, fn func(Event) error) error {
+	r, err := openLogFile(filePath)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	return ScanEvents(r, opts, fn)
+}
+
+// gzipFile closes both the gzip reader and the file beneath it.
+type gzipFile struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (g gzipFile) Close() error {
+	g.Reader.Close()
+	return g.file.Close()
+}
+
+// openLogFile opens a log file, decompressing it if its name ends in .gz.
+func openLogFile(filePath string) (io.ReadCloser, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("could not open log file %s: %w", filePath, err)
+	}
+	if !strings.HasSuffix(filePath, ".gz") {
+		return file, nil
+	}
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("could not decompress log file %s: %w", filePath, err)
+	}
+	return gzipFile{Reader: gz, file: file}, nil
+}
+
+// ScanLogs streams the typed events of several log files to fn, one file
+// after the other, e.g. the archives of a rotated log followed by the live
+// log. The files are filtered as one log, and returning ErrStopScan from fn
+// ends the whole scan.
+func ScanLogs(filePaths []string, opts ScanOptions, fn func(Event) error) error {
+	if opts.match == nil && !opts.Filter.IsZero() {
+		opts.match = opts.Filter.Matcher()
+	}
+	stopped := false
+	for _, filePath := range filePaths {
+		err := ScanLogFile(filePath, opts, func(event Event) error {
+			err := fn(event)
+			if errors.Is(err, ErrStopScan) {
+				stopped = true
+			}
+			return err
+		})
+		if err != nil || stopped {
+			return err
+		}
+	}
+	return nil
+}
+
+// ScanCommits streams only the text_committed events of the log files to fn.
+func ScanCommits(filePaths []string, opts ScanOptions, fn func(*TextCommittedEvent) error) error {
+	return ScanLogs(filePaths, opts, func(event Event) error {
+		if commit, ok := event.(*TextCommittedEvent); ok {
+			return fn(commit)
+		}
+		return nil
+	})
+}
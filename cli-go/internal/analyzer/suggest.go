@@ -0,0 +1,75 @@
+package analyzer
+
+import (
+	"sort"
+	"strings"
+)
+
+// SuggestOptions sets the thresholds a misprediction must pass before it is
+// turned into a dictionary suggestion.
+type SuggestOptions struct {
+	// MinCount is the minimum number of times the text was chosen over the first candidate.
+	MinCount int
+	// MinRank is the minimum mean selected rank; higher values only keep words buried deep in the menu.
+	MinRank float64
+}
+
+// DictSuggestion is a (text, input code) pair that Rime repeatedly failed to rank first.
+type DictSuggestion struct {
+	Text     string  `json:"text" yaml:"text"`
+	Code     string  `json:"code" yaml:"code"`
+	Count    int     `json:"count" yaml:"count"`
+	MeanRank float64 `json:"mean_rank" yaml:"mean_rank"`
+}
+
+// SuggestDictEntries merges misprediction pairs by input code and chosen text
+// (regardless of what Rime predicted) and keeps those passing the thresholds,
+// most frequent first.
+func SuggestDictEntries(pairs []MissPair, opts SuggestOptions) []DictSuggestion {
+	type key struct{ code, text string }
+	type agg struct {
+		count   int
+		rankSum float64
+	}
+
+	merged := make(map[key]*agg)
+	for _, p := range pairs {
+		code := strings.TrimSpace(p.Input)
+		text := strings.TrimSpace(p.Chosen)
+		if code == "" || text == "" || text == "N/A" {
+			continue
+		}
+		k := key{code: code, text: text}
+		a, ok := merged[k]
+		if !ok {
+			a = &agg{}
+			merged[k] = a
+		}
+		a.count += p.Count
+		a.rankSum += p.MeanRank * float64(p.Count)
+	}
+
+	var suggestions []DictSuggestion
+	for k, a := range merged {
+		meanRank := a.rankSum / float64(a.count)
+		if a.count < opts.MinCount || meanRank < opts.MinRank {
+			continue
+		}
+		suggestions = append(suggestions, DictSuggestion{
+			Text:     k.text,
+			Code:     k.code,
+			Count:    a.count,
+			MeanRank: meanRank,
+		})
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Count != suggestions[j].Count {
+			return suggestions[i].Count > suggestions[j].Count
+		}
+		if suggestions[i].Code != suggestions[j].Code {
+			return suggestions[i].Code < suggestions[j].Code
+		}
+		return suggestions[i].Text < suggestions[j].Text
+	})
+	return suggestions
+}
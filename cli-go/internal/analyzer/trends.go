@@ -0,0 +1,120 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Granularity is the width of a time bucket used for trend analysis.
+type Granularity string
+
+// Supported trend granularities for `analyze --by`.
+const (
+	ByHour  Granularity = "hour"
+	ByDay   Granularity = "day"
+	ByWeek  Granularity = "week"
+	ByMonth Granularity = "month"
+)
+
+// ParseGranularity validates a user-supplied granularity name.
+func ParseGranularity(value string) (Granularity, error) {
+	switch g := Granularity(value); g {
+	case ByHour, ByDay, ByWeek, ByMonth:
+		return g, nil
+	default:
+		return "", fmt.Errorf("unsupported granularity %q (expected hour, day, week or month)", value)
+	}
+}
+
+// Truncate returns the start of the bucket containing t, in local time.
+func (g Granularity) Truncate(t time.Time) time.Time {
+	t = t.Local()
+	y, m, d := t.Date()
+	switch g {
+	case ByHour:
+		return time.Date(y, m, d, t.Hour(), 0, 0, 0, t.Location())
+	case ByWeek:
+		// ISO weeks start on Monday.
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(y, m, d-offset, 0, 0, 0, 0, t.Location())
+	case ByMonth:
+		return time.Date(y, m, 1, 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+	}
+}
+
+// Label formats a bucket start for display.
+func (g Granularity) Label(start time.Time) string {
+	switch g {
+	case ByHour:
+		return start.Format("2006-01-02 15:00")
+	case ByWeek:
+		year, week := start.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	case ByMonth:
+		return start.Format("2006-01")
+	default:
+		return start.Format("2006-01-02")
+	}
+}
+
+// TrendBucket holds the metrics for one time bucket.
+type TrendBucket struct {
+	Start  time.Time      `json:"start" yaml:"start"`
+	Label  string         `json:"label" yaml:"label"`
+	Result AnalysisResult `json:"result" yaml:"result"`
+}
+
+// TrendTracker accumulates commits into time buckets of a fixed granularity.
+type TrendTracker struct {
+	granularity Granularity
+	buckets     map[time.Time]*Accumulator
+	untimed     int
+}
+
+// NewTrendTracker returns an empty TrendTracker for the given granularity.
+func NewTrendTracker(g Granularity) *TrendTracker {
+	return &TrendTracker{
+		granularity: g,
+		buckets:     make(map[time.Time]*Accumulator),
+	}
+}
+
+// Add records a single text_committed event in its bucket.
+// Events without a parseable timestamp are counted but not bucketed.
+func (t *TrendTracker) Add(event *TextCommittedEvent) {
+	ts := event.Time()
+	if ts.IsZero() {
+		t.untimed++
+		return
+	}
+	start := t.granularity.Truncate(ts)
+	acc, ok := t.buckets[start]
+	if !ok {
+		acc = NewAccumulator()
+		t.buckets[start] = acc
+	}
+	acc.Add(event)
+}
+
+// Granularity returns the bucket width the tracker was created with.
+func (t *TrendTracker) Granularity() Granularity { return t.granularity }
+
+// Untimed returns the number of commits skipped for lack of a timestamp.
+func (t *TrendTracker) Untimed() int { return t.untimed }
+
+// Buckets returns the non-empty buckets in chronological order.
+func (t *TrendTracker) Buckets() []TrendBucket {
+	buckets := make([]TrendBucket, 0, len(t.buckets))
+	for start, acc := range t.buckets {
+		buckets = append(buckets, TrendBucket{
+			Start:  start,
+			Label:  t.granularity.Label(start),
+			Result: acc.Result(),
+		})
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].Start.Before(buckets[j].Start) })
+	return buckets
+}
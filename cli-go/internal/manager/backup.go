@@ -0,0 +1,264 @@
+package manager
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// ToolVersion is recorded in backup manifests. Release builds may override it with
+// -ldflags "-X rime-wanxiang-logger-go/internal/manager.ToolVersion=...".
+var ToolVersion = "0.1.0"
+
+// Constants for the backup directory
+const (
+	BackupDirName    = "input_habit_logger_backups"
+	BackupManifest   = "manifest.json"
+	backupTimeFormat = "20060102-150405"
+)
+
+// ErrBackupNotFound is returned when no backup has the requested ID.
+var ErrBackupNotFound = errors.New("backup not found")
+
+// BackupFile is one file saved in a backup.
+type BackupFile struct {
+	OriginalPath string `json:"original_path" yaml:"original_path"`
+	// Stored is the name of the copy inside the backup directory.
+	Stored string `json:"stored,omitempty" yaml:"stored,omitempty"`
+	SHA256 string `json:"sha256,omitempty" yaml:"sha256,omitempty"`
+	Size   int64  `json:"size" yaml:"size"`
+	// Absent is true when the file did not exist yet; restoring removes it.
+	Absent bool `json:"absent,omitempty" yaml:"absent,omitempty"`
+}
+
+// Backup is a set of files saved before one run of the tool changed them,
+// described by the manifest.json in its directory.
+type Backup struct {
+	ID          string       `json:"id" yaml:"id"`
+	CreatedAt   time.Time    `json:"created_at" yaml:"created_at"`
+	ToolVersion string       `json:"tool_version" yaml:"tool_version"`
+	Reason      string       `json:"reason" yaml:"reason"`
+	Files       []BackupFile `json:"files" yaml:"files"`
+
+	dir string
+}
+
+// BackupSet collects the files touched by one operation into a single
+// Backup. Nothing is written until the first Add.
+type BackupSet struct {
+	m      *RimeManager
+	reason string
+	backup *Backup
+}
+
+// GetBackupDirectory returns the directory holding all backups.
+func (m *RimeManager) GetBackupDirectory() string {
+	return filepath.Join(m.UserDirectory, BackupDirName)
+}
+
+// NewBackupSet starts a backup for an operation described by reason, e.g. "install".
+func (m *RimeManager) NewBackupSet(reason string) *BackupSet {
+	return &BackupSet{m: m, reason: reason}
+}
+
+// ID returns the backup ID, or "" if no file has been added yet.
+func (b *BackupSet) ID() string {
+	if b == nil || b.backup == nil {
+		return ""
+	}
+	return b.backup.ID
+}
+
+// Add saves the current content of path before it is changed. A path that does
+// not exist yet is recorded as absent. Adding the same path twice keeps the
+// first copy, which is the state before the operation.
+func (b *BackupSet) Add(path string) error {
+	if b.backup == nil {
+		backup, err := b.m.createBackup(b.reason)
+		if err != nil {
+			return err
+		}
+		b.backup = backup
+	}
+
+	for _, f := range b.backup.Files {
+		if f.OriginalPath == path {
+			return nil
+		}
+	}
+
+	file := BackupFile{OriginalPath: path}
+	content, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+		file.Absent = true
+	case err != nil:
+		return fmt.Errorf("failed to read %s for backup: %w", path, err)
+	default:
+		file.Stored = fmt.Sprintf("%d-%s", len(b.backup.Files)+1, filepath.Base(path))
+		file.SHA256 = sha256Hex(content)
+		file.Size = int64(len(content))
+		if err := os.WriteFile(filepath.Join(b.backup.dir, file.Stored), content, 0644); err != nil {
+			return fmt.Errorf("failed to write backup of %s: %w", path, err)
+		}
+	}
+
+	b.backup.Files = append(b.backup.Files, file)
+	return b.backup.writeManifest()
+}
+
+// createBackup makes a new, uniquely named backup directory.
+func (m *RimeManager) createBackup(reason string) (*Backup, error) {
+	root := m.GetBackupDirectory()
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	now := time.Now()
+	base := now.Format(backupTimeFormat)
+	for n := 1; ; n++ {
+		id := base
+		if n > 1 {
+			id = fmt.Sprintf("%s-%d", base, n)
+		}
+		dir := filepath.Join(root, id)
+		err := os.Mkdir(dir, 0755)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to create backup directory: %w", err)
+		}
+		return &Backup{ID: id, CreatedAt: now, ToolVersion: ToolVersion, Reason: reason, dir: dir}, nil
+	}
+}
+
+func (b *Backup) writeManifest() error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(b.dir, BackupManifest), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write backup manifest: %w", err)
+	}
+	return nil
+}
+
+// ListBackups returns every backup with a readable manifest, newest first.
+func (m *RimeManager) ListBackups() ([]Backup, error) {
+	entries, err := os.ReadDir(m.GetBackupDirectory())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+
+	var backups []Backup
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		backup, err := m.loadBackup(e.Name())
+		if err != nil {
+			continue
+		}
+		backups = append(backups, *backup)
+	}
+
+	sort.SliceStable(backups, func(i, j int) bool {
+		if !backups[i].CreatedAt.Equal(backups[j].CreatedAt) {
+			return backups[i].CreatedAt.After(backups[j].CreatedAt)
+		}
+		return backups[i].ID > backups[j].ID
+	})
+	return backups, nil
+}
+
+// GetBackup loads the backup with the given ID.
+func (m *RimeManager) GetBackup(id string) (*Backup, error) {
+	if id == "" || filepath.Base(id) != id {
+		return nil, fmt.Errorf("%w: %q", ErrBackupNotFound, id)
+	}
+	backup, err := m.loadBackup(id)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %q", ErrBackupNotFound, id)
+	}
+	return backup, err
+}
+
+func (m *RimeManager) loadBackup(id string) (*Backup, error) {
+	dir := filepath.Join(m.GetBackupDirectory(), id)
+	data, err := os.ReadFile(filepath.Join(dir, BackupManifest))
+	if err != nil {
+		return nil, err
+	}
+	var backup Backup
+	if err := json.Unmarshal(data, &backup); err != nil {
+		return nil, fmt.Errorf("invalid manifest in backup %s: %w", id, err)
+	}
+	backup.ID = id
+	backup.dir = dir
+	return &backup, nil
+}
+
+// RestoreBackup puts every file of a backup back in place, removing files the
+// backup recorded as absent. The stored copies are verified against their
+// sha256 first, and the files about to be overwritten are saved to a new
+// backup set (reason "restore <id>") so the restore itself can be undone.
+// It returns that new set.
+func (m *RimeManager) RestoreBackup(id string) (*BackupSet, error) {
+	backup, err := m.GetBackup(id)
+	if err != nil {
+		return nil, err
+	}
+
+	contents := make([][]byte, len(backup.Files))
+	for i, f := range backup.Files {
+		if f.Absent {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(backup.dir, f.Stored))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read backup copy of %s: %w", f.OriginalPath, err)
+		}
+		if sha256Hex(content) != f.SHA256 {
+			return nil, fmt.Errorf("backup copy of %s is corrupted (sha256 mismatch)", f.OriginalPath)
+		}
+		contents[i] = content
+	}
+
+	undo := m.NewBackupSet("restore " + id)
+	for _, f := range backup.Files {
+		if err := undo.Add(f.OriginalPath); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, f := range backup.Files {
+		if f.Absent {
+			if err := os.Remove(f.OriginalPath); err != nil && !os.IsNotExist(err) {
+				return undo, fmt.Errorf("failed to remove %s: %w", f.OriginalPath, err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(f.OriginalPath), 0755); err != nil {
+			return undo, fmt.Errorf("failed to create directory for %s: %w", f.OriginalPath, err)
+		}
+		if err := os.WriteFile(f.OriginalPath, contents[i], 0644); err != nil {
+			return undo, fmt.Errorf("failed to restore %s: %w", f.OriginalPath, err)
+		}
+	}
+	return undo, nil
+}
+
+func sha256Hex(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
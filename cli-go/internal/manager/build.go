@@ -0,0 +1,154 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// BuildDirName is the directory Rime compiles schemas into when deploying.
+const BuildDirName = "build"
+
+// ErrNotDeployed is returned when a schema has no compiled copy in build/.
+var ErrNotDeployed = errors.New("schema has not been deployed")
+
+// GetBuildDirectory returns the build/ directory of the user directory.
+func (m *RimeManager) GetBuildDirectory() string {
+	return filepath.Join(m.UserDirectory, BuildDirName)
+}
+
+// GetBuildSchemaPath returns the path to the compiled build/<schemaID>.schema.yaml.
+func (m *RimeManager) GetBuildSchemaPath(schemaID string) string {
+	return filepath.Join(m.GetBuildDirectory(), SchemaFileName(schemaID))
+}
+
+// CheckBuildConfigured checks if the deployed schema, with every patch
+// applied, lists the logger in engine/processors. It returns ErrNotDeployed
+// if the schema has not been compiled.
+func (m *RimeManager) CheckBuildConfigured(schemaID string) (bool, error) {
+	path := m.GetBuildSchemaPath(schemaID)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, fmt.Errorf("%w: %s", ErrNotDeployed, path)
+		}
+		return false, fmt.Errorf("could not read compiled schema: %w", err)
+	}
+
+	src, err := parseYAMLSource(content)
+	if err != nil {
+		return false, fmt.Errorf("could not parse %s/%s: %w", BuildDirName, SchemaFileName(schemaID), err)
+	}
+	processors := schemaProcessors(src.root())
+	return sequenceIndex(processors, func(v string) bool { return v == LoggerProcessor }) >= 0, nil
+}
+
+// userYAML is the subset of user.yaml where Rime records the last deployment.
+type userYAML struct {
+	Var struct {
+		LastBuildTime int64 `yaml:"last_build_time"`
+	} `yaml:"var"`
+}
+
+// LastDeployTime returns when Rime last deployed the user directory, from
+// var/last_build_time in user.yaml, or else the newest compiled schema in
+// build/. It returns the zero time if Rime has never deployed.
+func (m *RimeManager) LastDeployTime() (time.Time, error) {
+	if content, err := os.ReadFile(filepath.Join(m.UserDirectory, "user.yaml")); err == nil {
+		var user userYAML
+		if yaml.Unmarshal(content, &user) == nil && user.Var.LastBuildTime > 0 {
+			return time.Unix(user.Var.LastBuildTime, 0), nil
+		}
+	}
+
+	return m.lastBuildModTime()
+}
+
+// lastBuildModTime returns the modification time of the newest compiled
+// schema in build/, or the zero time if there is none.
+func (m *RimeManager) lastBuildModTime() (time.Time, error) {
+	built, err := filepath.Glob(filepath.Join(m.GetBuildDirectory(), "*"+SchemaFileSuffix))
+	if err != nil {
+		return time.Time{}, err
+	}
+	var latest time.Time
+	for _, path := range built {
+		if info, err := os.Stat(path); err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// DeployState compares the deployed copy of a schema with its sources.
+type DeployState string
+
+// Deploy states
+const (
+	DeployCurrent     DeployState = "current"      // build/ matches the schema and its patch
+	DeployPending     DeployState = "pending"      // the sources changed since the last deployment
+	DeployNotDeployed DeployState = "not_deployed" // build/ has no copy of the schema
+)
+
+// BuildStatus reports whether the logger is configured in a schema's
+// sources and whether the deployed engine actually contains it.
+type BuildStatus struct {
+	Schema     string      `json:"schema" yaml:"schema"`
+	Configured bool        `json:"configured" yaml:"configured"`
+	Deployed   bool        `json:"deployed" yaml:"deployed"`
+	State      DeployState `json:"state" yaml:"state"`
+	Path       string      `json:"path" yaml:"path"`
+}
+
+// CheckBuildStatus compares the logger configuration in the schema file and
+// <schemaID>.custom.yaml with the compiled schema in build/. A configured
+// schema that was never compiled is pending as well.
+func (m *RimeManager) CheckBuildStatus(schemaID string) (BuildStatus, error) {
+	status := BuildStatus{Schema: schemaID, Path: m.GetBuildSchemaPath(schemaID)}
+
+	patched, err := m.CheckPatchConfigured(schemaID)
+	if err != nil {
+		return status, err
+	}
+	status.Configured = patched
+	if !patched {
+		// The schema file may only exist in the shared data directory
+		if _, err := os.Stat(m.GetSchemaPath(schemaID)); err == nil {
+			if status.Configured, err = m.CheckSchemaConfigured(schemaID); err != nil {
+				return status, err
+			}
+		}
+	}
+
+	status.Deployed, err = m.CheckBuildConfigured(schemaID)
+	switch {
+	case errors.Is(err, ErrNotDeployed):
+		status.State = DeployNotDeployed
+		if status.Configured {
+			status.State = DeployPending
+		}
+	case err != nil:
+		return status, err
+	case status.Configured != status.Deployed:
+		status.State = DeployPending
+	default:
+		status.State = DeployCurrent
+	}
+	return status, nil
+}
+
+// PendingRedeploy returns the schemas among schemaIDs whose deployed engine
+// does not match their sources. Schemas that cannot be read are skipped.
+func (m *RimeManager) PendingRedeploy(schemaIDs []string) []string {
+	var pending []string
+	for _, schemaID := range schemaIDs {
+		if status, err := m.CheckBuildStatus(schemaID); err == nil && status.State == DeployPending {
+			pending = append(pending, schemaID)
+		}
+	}
+	return pending
+}
@@ -0,0 +1,274 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// LogEvents lists the event types written by input_habit_logger.lua.
+var LogEvents = []string{"session_start", "session_end", "text_committed", "input_state_changed", "error"}
+
+// LogEventFields lists the fields each event type can log, as used in log_fields.
+var LogEventFields = map[string][]string{
+	"session_start": {"schema_id"},
+	"session_end":   {},
+	"text_committed": {
+		"committed_text", "input_sequence_at_commit", "selection_method", "selected_candidate_rank",
+		"source_input_buffer", "source_first_candidate", "source_candidates_list", "source_event_timestamp",
+	},
+	"input_state_changed": {"key_action", "input_buffer", "first_candidate", "has_menu", "candidates"},
+	"error":               {"component", "message", "key_repr"},
+}
+
+// InputStateSubtypes lists the values of event_subtype of input_state_changed
+// events, as used in log_fields.input_state_changed.event_subtype.
+var InputStateSubtypes = []string{"menu_navigation", "input_rejected", "manual_segmentation", "buffer_edit", "other_key"}
+
+// Config keys with a meaning of their own. The others are log_events.<event>,
+// log_fields.<event>.<field> and log_fields.input_state_changed.event_subtype.<subtype>.
+const (
+	ConfigKeyPreset                = "preset"
+	ConfigKeyEnabled               = "enabled"
+	ConfigKeyLogOnlyNonFirstChoice = "log_only_non_first_choice"
+	ConfigKeyLogFilePath           = "log_file_path"
+)
+
+// ErrUnknownConfigKey is returned for keys that the logger does not read.
+var ErrUnknownConfigKey = errors.New("unknown config key")
+
+// ConfigChange is the result of SetConfigValue.
+type ConfigChange struct {
+	Key string `json:"key" yaml:"key"`
+	// Preset is the preset table that was edited, empty for the preset choice.
+	Preset string `json:"preset,omitempty" yaml:"preset,omitempty"`
+	// Old is the Lua literal previously written, empty if the key was not set.
+	Old string `json:"old,omitempty" yaml:"old,omitempty"`
+	New string `json:"new" yaml:"new"`
+}
+
+// ParseConfigKey validates a dotted config key against the known events,
+// fields and subtypes and returns its path inside a preset table. The preset
+// key returns nil.
+func ParseConfigKey(key string) ([]string, error) {
+	path := strings.Split(key, ".")
+	switch {
+	case key == ConfigKeyPreset || key == "preset_choice":
+		return nil, nil
+	case key == ConfigKeyEnabled || key == ConfigKeyLogOnlyNonFirstChoice || key == ConfigKeyLogFilePath:
+		return path, nil
+	case len(path) == 2 && path[0] == "log_events":
+		if _, ok := LogEventFields[path[1]]; ok {
+			return path, nil
+		}
+		return nil, fmt.Errorf("%w %q: unknown event %q (expected one of: %s)", ErrUnknownConfigKey, key, path[1], strings.Join(LogEvents, ", "))
+	case len(path) >= 3 && path[0] == "log_fields":
+		fields, ok := LogEventFields[path[1]]
+		if !ok {
+			return nil, fmt.Errorf("%w %q: unknown event %q (expected one of: %s)", ErrUnknownConfigKey, key, path[1], strings.Join(LogEvents, ", "))
+		}
+		if path[1] == "input_state_changed" && path[2] == "event_subtype" {
+			if len(path) == 4 && containsString(InputStateSubtypes, path[3]) {
+				return path, nil
+			}
+			return nil, fmt.Errorf("%w %q: expected log_fields.input_state_changed.event_subtype.<%s>", ErrUnknownConfigKey, key, strings.Join(InputStateSubtypes, "|"))
+		}
+		if len(path) == 3 && containsString(fields, path[2]) {
+			return path, nil
+		}
+		return nil, fmt.Errorf("%w %q: event %s has the fields: %s", ErrUnknownConfigKey, key, path[1], strings.Join(fields, ", "))
+	default:
+		return nil, fmt.Errorf("%w %q (expected preset, enabled, log_only_non_first_choice, log_file_path, log_events.<event> or log_fields.<event>.<field>)", ErrUnknownConfigKey, key)
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// configLiteral converts a command line value for the key at path into a Lua
+// literal. log_file_path takes any string, with "" and "nil" resetting it to
+// the default; every other key takes a boolean.
+func configLiteral(path []string, value string) (string, luaValue, error) {
+	if len(path) == 1 && path[0] == ConfigKeyLogFilePath {
+		if value == "" || value == "nil" {
+			return "nil", nil, nil
+		}
+		return quoteLuaString(value), value, nil
+	}
+	switch strings.ToLower(value) {
+	case "on", "yes":
+		return "true", true, nil
+	case "off", "no":
+		return "false", false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid value %q for %s: expected true or false", value, strings.Join(path, "."))
+	}
+	return strconv.FormatBool(b), b, nil
+}
+
+// SetConfigValue rewrites the source of input_habit_logger_config.lua so
+// that key has value. The preset key changes preset_choice; every other key is
+// set in the table of preset, or of the preset in effect when preset is "".
+// Missing tables are created inside the closest existing one. Only the edited
+// value changes; comments and layout are kept. The result is evaluated again
+// to make sure the change took effect.
+func SetConfigValue(src []byte, key, value, preset string) ([]byte, *ConfigChange, error) {
+	path, err := ParseConfigKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	chunk, err := parseLua(src)
+	if err != nil {
+		return nil, nil, err
+	}
+	current, err := ParseLoggerConfig(src)
+	if err != nil {
+		return nil, nil, err
+	}
+	_, env, _ := evalLua(chunk)
+	presets, _ := env.vars["presets"].(*luaTable)
+
+	if path == nil {
+		if presets == nil || presets.get(value) == nil {
+			return nil, nil, fmt.Errorf("preset %q is not defined in %s", value, ConfigLuaFile)
+		}
+		return setLuaSetting(chunk, []string{"preset_choice"}, quoteLuaString(value), value, &ConfigChange{Key: ConfigKeyPreset})
+	}
+
+	if preset == "" {
+		preset = current.Preset
+	}
+	if preset == "" {
+		return nil, nil, fmt.Errorf("%s does not return one of its presets; edit it by hand", ConfigLuaFile)
+	}
+	if presets == nil || presets.get(preset) == nil {
+		return nil, nil, fmt.Errorf("preset %q is not defined in %s", preset, ConfigLuaFile)
+	}
+
+	literal, want, err := configLiteral(path, value)
+	if err != nil {
+		return nil, nil, err
+	}
+	full := append([]string{"presets", preset}, path...)
+	return setLuaSetting(chunk, full, literal, want, &ConfigChange{Key: key, Preset: preset})
+}
+
+// setLuaSetting writes literal at path and checks that the value of path
+// becomes want once the edited source is evaluated.
+func setLuaSetting(chunk *luaChunk, path []string, literal string, want luaValue, change *ConfigChange) ([]byte, *ConfigChange, error) {
+	change.New = literal
+
+	var edit luaEdit
+	if s, ok := chunk.setting(path); ok {
+		if s.isTable() {
+			return nil, nil, fmt.Errorf("%s is a table, not a single value", s.key())
+		}
+		change.Old = chunk.text(s.expr)
+		edit = replaceEdit(s, literal)
+	} else {
+		// Find the closest enclosing table and build the missing levels inline
+		depth := len(path) - 1
+		var parent luaSetting
+		for ; depth > 0; depth-- {
+			if s, ok := chunk.setting(path[:depth]); ok {
+				parent = s
+				break
+			}
+		}
+		if depth == 0 {
+			return nil, nil, fmt.Errorf("%s is not set in %s; edit it by hand", path[0], ConfigLuaFile)
+		}
+		if !parent.isTable() {
+			return nil, nil, fmt.Errorf("%s is not a table in %s", parent.key(), ConfigLuaFile)
+		}
+		value := literal
+		for i := len(path) - 1; i > depth; i-- {
+			value = "{ " + luaFieldKey(path[i]) + " = " + value + " }"
+		}
+		edit = chunk.insertFieldEdit(parent.expr, path[depth], value)
+	}
+
+	updated, err := applyLuaEdits(chunk.src, []luaEdit{edit})
+	if err != nil {
+		return nil, nil, err
+	}
+	check, err := parseLua([]byte(updated))
+	if err != nil {
+		return nil, nil, fmt.Errorf("edit produced an invalid config: %w", err)
+	}
+	_, env, err := evalLua(check)
+	if err != nil {
+		return nil, nil, fmt.Errorf("edit produced an invalid config: %w", err)
+	}
+	var got luaValue = env.vars[path[0]]
+	for _, k := range path[1:] {
+		t, ok := got.(*luaTable)
+		if !ok {
+			got = nil
+			break
+		}
+		got = t.get(k)
+	}
+	if got != want {
+		return nil, nil, fmt.Errorf("%s is overridden later in %s; edit it by hand", strings.Join(path, "."), ConfigLuaFile)
+	}
+	return []byte(updated), change, nil
+}
+
+// PlanConfigSet plans setting key to value in the installed config file; see
+// SetConfigValue. Without a config file the embedded template is used as a
+// starting point.
+func (m *RimeManager) PlanConfigSet(p *Plan, template []byte, key, value, preset string) (*ConfigChange, error) {
+	path := m.GetConfigPath()
+	content, exists, err := p.Content(path)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		content = template
+	}
+
+	updated, change, err := SetConfigValue(content, key, value, preset)
+	if err != nil {
+		return nil, err
+	}
+	return change, p.Write(path, updated)
+}
+
+// ConfigValue returns the effective value of a config key, formatted as a
+// Lua literal.
+func (c *LoggerConfig) ConfigValue(key string) (string, error) {
+	path, err := ParseConfigKey(key)
+	if err != nil {
+		return "", err
+	}
+	if path == nil {
+		return quoteLuaString(c.Preset), nil
+	}
+	switch path[0] {
+	case ConfigKeyEnabled:
+		return strconv.FormatBool(c.Enabled), nil
+	case ConfigKeyLogOnlyNonFirstChoice:
+		return strconv.FormatBool(c.LogOnlyNonFirstChoice), nil
+	case ConfigKeyLogFilePath:
+		if c.LogFilePath == "" {
+			return "nil", nil
+		}
+		return quoteLuaString(c.LogFilePath), nil
+	case "log_events":
+		return strconv.FormatBool(c.LogEvents[path[1]]), nil
+	}
+	if path[2] == "event_subtype" {
+		return strconv.FormatBool(c.EventSubtypes[path[3]]), nil
+	}
+	return strconv.FormatBool(c.LogFields[path[1]][path[2]]), nil
+}
@@ -0,0 +1,214 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Deployer names
+const (
+	DeployerRimeDeployer = "rime_deployer"
+	DeployerFcitx5       = "fcitx5"
+	DeployerFcitx        = "fcitx"
+	DeployerIBus         = "ibus"
+)
+
+// ErrNoDeployer is returned when no deployer is available for the user directory.
+var ErrNoDeployer = errors.New("no way to redeploy Rime was found on this system")
+
+// ErrDeployTimeout is returned by Redeploy when the deployment is not seen
+// to finish in time.
+var ErrDeployTimeout = errors.New("deployment was not confirmed in time")
+
+// Deployer triggers a Rime deployment of a user directory.
+type Deployer interface {
+	// Name identifies the deployer, e.g. for --deployer.
+	Name() string
+	// Available reports whether the deployer can run on this system.
+	Available() bool
+	// Deploy starts the deployment. Frontends that deploy in the background
+	// may return before build/ has been updated.
+	Deploy(userDir string) error
+}
+
+// commandDeployer runs an external program to deploy.
+type commandDeployer struct {
+	name    string
+	program string
+	args    func(userDir string) []string
+}
+
+func (d *commandDeployer) Name() string { return d.name }
+
+func (d *commandDeployer) Available() bool {
+	_, err := exec.LookPath(d.program)
+	return err == nil
+}
+
+func (d *commandDeployer) Deploy(userDir string) error {
+	cmd := exec.Command(d.program, d.args(userDir)...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if out := strings.TrimSpace(string(output)); out != "" {
+			return fmt.Errorf("%s failed: %w: %s", d.program, err, out)
+		}
+		return fmt.Errorf("%s failed: %w", d.program, err)
+	}
+	return nil
+}
+
+// sharedDataDirs are the usual locations of the shared rime-data directory.
+var sharedDataDirs = []string{
+	"/usr/share/rime-data",
+	"/usr/local/share/rime-data",
+	"/run/current-system/sw/share/rime-data",
+}
+
+// rimeDeployerArgs builds 'rime_deployer --build <userDir> [<sharedDataDir>]'.
+func rimeDeployerArgs(userDir string) []string {
+	args := []string{"--build", userDir}
+	for _, dir := range sharedDataDirs {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return append(args, dir)
+		}
+	}
+	return args
+}
+
+// Deployers returns every built-in deployer, in order of preference.
+func Deployers() []Deployer {
+	return []Deployer{
+		&commandDeployer{name: DeployerRimeDeployer, program: "rime_deployer", args: rimeDeployerArgs},
+		&commandDeployer{name: DeployerFcitx5, program: "fcitx5-remote", args: func(string) []string { return []string{"-r"} }},
+		&commandDeployer{name: DeployerFcitx, program: "fcitx-remote", args: func(string) []string { return []string{"-r"} }},
+		&commandDeployer{name: DeployerIBus, program: "ibus", args: func(string) []string { return []string{"restart"} }},
+	}
+}
+
+// frontendDeployers maps a frontend to the deployers that reload it, best first.
+// rime_deployer compiles any user directory, so it is the fallback for all.
+var frontendDeployers = map[string][]string{
+	FrontendFcitx5: {DeployerFcitx5, DeployerRimeDeployer},
+	FrontendFcitx:  {DeployerFcitx, DeployerRimeDeployer},
+	FrontendIBus:   {DeployerIBus, DeployerRimeDeployer},
+}
+
+// Frontend returns the name of the frontend that owns the user directory, or
+// "" if it is not one of the detected frontend directories.
+func (m *RimeManager) Frontend() string {
+	for _, f := range DetectFrontends() {
+		if f.Directory == m.UserDirectory {
+			return f.Name
+		}
+	}
+	return ""
+}
+
+// SelectDeployer returns the deployer called name from deployers, or, when
+// name is empty, the first available one suited to the user directory's
+// frontend.
+func (m *RimeManager) SelectDeployer(deployers []Deployer, name string) (Deployer, error) {
+	byName := make(map[string]Deployer, len(deployers))
+	for _, d := range deployers {
+		byName[d.Name()] = d
+	}
+
+	if name != "" {
+		d, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown deployer %q", name)
+		}
+		if !d.Available() {
+			return nil, fmt.Errorf("deployer %q is not available on this system", name)
+		}
+		return d, nil
+	}
+
+	preferred, ok := frontendDeployers[m.Frontend()]
+	if !ok {
+		preferred = []string{DeployerRimeDeployer}
+	}
+	for _, n := range preferred {
+		if d, ok := byName[n]; ok && d.Available() {
+			return d, nil
+		}
+	}
+	return nil, ErrNoDeployer
+}
+
+// deployPollInterval is how often Redeploy re-checks build/.
+var deployPollInterval = 500 * time.Millisecond
+
+// Redeploy runs the deployer and then waits up to timeout for the deployment
+// to finish: Rime must record a newer deployment than before (see
+// LastDeployTime) or rewrite a compiled schema, and build/ must match the
+// sources of schemaIDs (see CheckBuildStatus). If that does not happen in
+// time it returns ErrDeployTimeout along with the schemas still pending.
+func (m *RimeManager) Redeploy(d Deployer, schemaIDs []string, timeout time.Duration) ([]string, error) {
+	lastDeploy, _ := m.LastDeployTime()
+	lastBuild, _ := m.lastBuildModTime()
+
+	if err := d.Deploy(m.UserDirectory); err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		pending := m.PendingRedeploy(schemaIDs)
+		if len(pending) == 0 && m.deployedSince(lastDeploy, lastBuild) {
+			return nil, nil
+		}
+		if !time.Now().Before(deadline) {
+			return pending, ErrDeployTimeout
+		}
+		time.Sleep(deployPollInterval)
+	}
+}
+
+// deployedSince reports whether Rime has deployed since LastDeployTime
+// returned lastDeploy and the newest compiled schema was from lastBuild.
+func (m *RimeManager) deployedSince(lastDeploy, lastBuild time.Time) bool {
+	if t, err := m.LastDeployTime(); err == nil && t.After(lastDeploy) {
+		return true
+	}
+	t, err := m.lastBuildModTime()
+	return err == nil && t.After(lastBuild)
+}
+
+// FakeDeployer is a Deployer for tests. It records every deployment and
+// calls Build, if set, in place of a real frontend.
+type FakeDeployer struct {
+	FakeName string
+	// Unavailable makes Available report false.
+	Unavailable bool
+	// Err is returned by Deploy without calling Build.
+	Err error
+	// Build simulates the deployment, e.g. by writing build/*.schema.yaml.
+	Build func(userDir string) error
+	// Calls holds the user directory of every Deploy call.
+	Calls []string
+}
+
+func (d *FakeDeployer) Name() string {
+	if d.FakeName == "" {
+		return "fake"
+	}
+	return d.FakeName
+}
+
+func (d *FakeDeployer) Available() bool { return !d.Unavailable }
+
+func (d *FakeDeployer) Deploy(userDir string) error {
+	d.Calls = append(d.Calls, userDir)
+	if d.Err != nil {
+		return d.Err
+	}
+	if d.Build != nil {
+		return d.Build(userDir)
+	}
+	return nil
+}
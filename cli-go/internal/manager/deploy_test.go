@@ -0,0 +1,164 @@
+package manager
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// fakeDeployers returns an available FakeDeployer for every built-in deployer
+// name, except those listed as unavailable.
+func fakeDeployers(unavailable ...string) []Deployer {
+	var deployers []Deployer
+	for _, name := range []string{DeployerRimeDeployer, DeployerFcitx5, DeployerFcitx, DeployerIBus} {
+		deployers = append(deployers, &FakeDeployer{FakeName: name, Unavailable: slices.Contains(unavailable, name)})
+	}
+	return deployers
+}
+
+func TestSelectDeployerByFrontend(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the fcitx, fcitx5 and ibus directories are only detected on Linux")
+	}
+
+	tests := []struct {
+		name        string
+		dir         string // relative to $HOME
+		unavailable []string
+		want        string
+	}{
+		{"fcitx5", ".config/fcitx5/rime", nil, DeployerFcitx5},
+		{"fcitx5 data dir", ".local/share/fcitx5/rime", nil, DeployerFcitx5},
+		{"fcitx", ".config/fcitx/rime", nil, DeployerFcitx},
+		{"ibus", ".config/ibus/rime", nil, DeployerIBus},
+		{"plain rime dir", ".config/rime", nil, DeployerRimeDeployer},
+		{"unknown dir", "elsewhere/rime", nil, DeployerRimeDeployer},
+		{"frontend tool missing", ".config/fcitx5/rime", []string{DeployerFcitx5}, DeployerRimeDeployer},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			dir := filepath.Join(home, filepath.FromSlash(tt.dir))
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			m := &RimeManager{UserDirectory: dir}
+			d, err := m.SelectDeployer(fakeDeployers(tt.unavailable...), "")
+			if err != nil {
+				t.Fatalf("SelectDeployer: %v", err)
+			}
+			if d.Name() != tt.want {
+				t.Errorf("selected %s, want %s", d.Name(), tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectDeployerErrors(t *testing.T) {
+	m := &RimeManager{UserDirectory: t.TempDir()}
+
+	if d, err := m.SelectDeployer(fakeDeployers(), DeployerIBus); err != nil || d.Name() != DeployerIBus {
+		t.Errorf("by name: got %v, %v", d, err)
+	}
+	if _, err := m.SelectDeployer(fakeDeployers(), "kde"); err == nil {
+		t.Error("expected an error for an unknown deployer")
+	}
+	if _, err := m.SelectDeployer(fakeDeployers(DeployerFcitx), DeployerFcitx); err == nil {
+		t.Error("expected an error for an unavailable deployer")
+	}
+	if _, err := m.SelectDeployer(fakeDeployers(DeployerRimeDeployer), ""); !errors.Is(err, ErrNoDeployer) {
+		t.Errorf("err = %v, want ErrNoDeployer", err)
+	}
+}
+
+// loggerSchema is a schema with the logger configured.
+const loggerSchema = "schema:\n  schema_id: demo\nengine:\n  processors:\n    - " + LoggerProcessor + "\n    - speller\n"
+
+// writeBuild returns a FakeDeployer.Build that compiles content into
+// build/demo.schema.yaml.
+func writeBuild(content string) func(string) error {
+	return func(userDir string) error {
+		buildDir := filepath.Join(userDir, BuildDirName)
+		if err := os.MkdirAll(buildDir, 0755); err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(buildDir, SchemaFileName("demo")), []byte(content), 0644)
+	}
+}
+
+func TestRedeploy(t *testing.T) {
+	defer func(interval time.Duration) { deployPollInterval = interval }(deployPollInterval)
+	deployPollInterval = 10 * time.Millisecond
+
+	deployErr := errors.New("fcitx5-remote failed")
+	tests := []struct {
+		name        string
+		deployer    *FakeDeployer
+		wantPending []string
+		wantErr     error
+	}{
+		{"build updated", &FakeDeployer{Build: writeBuild(loggerSchema)}, nil, nil},
+		{"deployer fails", &FakeDeployer{Err: deployErr}, nil, deployErr},
+		{"nothing deployed", &FakeDeployer{}, []string{"demo"}, ErrDeployTimeout},
+		{"build without the logger", &FakeDeployer{Build: writeBuild("engine:\n  processors:\n    - speller\n")}, []string{"demo"}, ErrDeployTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &RimeManager{UserDirectory: t.TempDir()}
+			if err := os.WriteFile(m.GetSchemaPath("demo"), []byte(loggerSchema), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			pending, err := m.Redeploy(tt.deployer, []string{"demo"}, 50*time.Millisecond)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if !slices.Equal(pending, tt.wantPending) {
+				t.Errorf("pending = %v, want %v", pending, tt.wantPending)
+			}
+			if len(tt.deployer.Calls) != 1 || tt.deployer.Calls[0] != m.UserDirectory {
+				t.Errorf("Calls = %v, want one call for %s", tt.deployer.Calls, m.UserDirectory)
+			}
+		})
+	}
+}
+
+func TestRedeployWaitsForNewDeployment(t *testing.T) {
+	defer func(interval time.Duration) { deployPollInterval = interval }(deployPollInterval)
+	deployPollInterval = 10 * time.Millisecond
+
+	// build/ already matches the sources, so only a newer deployment counts
+	m := &RimeManager{UserDirectory: t.TempDir()}
+	if err := os.WriteFile(m.GetSchemaPath("demo"), []byte(loggerSchema), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeBuild(loggerSchema)(m.UserDirectory); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(m.GetBuildSchemaPath("demo"), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.Redeploy(&FakeDeployer{}, []string{"demo"}, 50*time.Millisecond); !errors.Is(err, ErrDeployTimeout) {
+		t.Errorf("deployer that did nothing: err = %v, want ErrDeployTimeout", err)
+	}
+
+	// Rime recording the deployment in user.yaml is enough
+	recordDeploy := func(userDir string) error {
+		content := "var:\n  last_build_time: " + strconv.FormatInt(time.Now().Add(time.Minute).Unix(), 10) + "\n"
+		return os.WriteFile(filepath.Join(userDir, "user.yaml"), []byte(content), 0644)
+	}
+	if _, err := m.Redeploy(&FakeDeployer{Build: recordDeploy}, []string{"demo"}, 50*time.Millisecond); err != nil {
+		t.Errorf("deployer that updated user.yaml: %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+package manager
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CustomPhraseFile is the plain-text phrase table read by Rime's custom_phrase translator.
+const CustomPhraseFile = "custom_phrase.txt"
+
+// DictEntry is a single row of a Rime phrase table or dictionary.
+type DictEntry struct {
+	Text   string
+	Code   string
+	Weight int
+}
+
+// GetCustomPhrasePath returns the path to custom_phrase.txt in the user directory.
+func (m *RimeManager) GetCustomPhrasePath() string {
+	return filepath.Join(m.UserDirectory, CustomPhraseFile)
+}
+
+// GetDictPath returns the path to <name>.dict.yaml in the user directory.
+func (m *RimeManager) GetDictPath(name string) string {
+	return filepath.Join(m.UserDirectory, name+".dict.yaml")
+}
+
+// CustomPhraseCode converts a logged input buffer into a custom_phrase code,
+// which must match the raw keys typed, so syllable separators are removed.
+func CustomPhraseCode(input string) string {
+	return strings.NewReplacer(" ", "", "'", "").Replace(input)
+}
+
+// DictCode converts a logged input buffer into a dictionary code, where
+// syllables are separated by single spaces.
+func DictCode(input string) string {
+	return strings.Join(strings.Fields(strings.ReplaceAll(input, "'", " ")), " ")
+}
+
+// PlanCustomPhrases plans appending entries to a custom_phrase.txt file,
+// creating it with the standard Rime table header if needed. Entries whose
+// text and code already appear in the file are skipped so that existing user
+// phrases and weights are never changed. It returns the entries to be added.
+func PlanCustomPhrases(plan *Plan, path string, entries []DictEntry) ([]DictEntry, error) {
+	content, exists, err := plan.Content(path)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		content = []byte(customPhraseHeader())
+	}
+	return planDictAppend(plan, path, content, existingDictEntries(content), entries)
+}
+
+// PlanDictEntries plans writing entries to the standalone dictionary name at
+// path. A new file is rendered by RenderDictYAML; an existing one keeps its
+// header and entries, and only entries whose text and code are not in it yet
+// are appended. A file without the "..." line ending the YAML header is not a
+// Rime dictionary and is left alone. It returns the entries to be added.
+func PlanDictEntries(plan *Plan, path, name string, entries []DictEntry) ([]DictEntry, error) {
+	content, exists, err := plan.Content(path)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		if err := plan.Write(path, RenderDictYAML(name, entries)); err != nil {
+			return nil, err
+		}
+		return entries, nil
+	}
+
+	body, ok := dictBody(content)
+	if !ok {
+		return nil, fmt.Errorf("%s exists but is not a Rime dictionary (no '...' ending its header)", path)
+	}
+	return planDictAppend(plan, path, content, existingDictEntries(body), entries)
+}
+
+// planDictAppend plans appending the entries missing from existing to content,
+// under a dated comment line.
+func planDictAppend(plan *Plan, path string, content []byte, existing map[string]bool, entries []DictEntry) ([]DictEntry, error) {
+	var added []DictEntry
+	var buf bytes.Buffer
+	for _, e := range entries {
+		key := e.Text + "\t" + e.Code
+		if existing[key] {
+			continue
+		}
+		existing[key] = true
+		added = append(added, e)
+		fmt.Fprintf(&buf, "%s\t%s\t%d\n", e.Text, e.Code, e.Weight)
+	}
+	if len(added) == 0 {
+		return nil, nil
+	}
+
+	updated := append([]byte(nil), content...)
+	if len(updated) > 0 && updated[len(updated)-1] != '\n' {
+		updated = append(updated, '\n')
+	}
+	updated = append(updated, []byte(fmt.Sprintf("# rime-logger suggest-dict %s\n", time.Now().Format("2006-01-02")))...)
+	updated = append(updated, buf.Bytes()...)
+
+	if err := plan.Write(path, updated); err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+// existingDictEntries returns the "text\tcode" keys of the table rows in content.
+func existingDictEntries(content []byte) map[string]bool {
+	existing := make(map[string]bool)
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) >= 2 {
+			existing[fields[0]+"\t"+fields[1]] = true
+		}
+	}
+	return existing
+}
+
+// dictBody returns the table rows of a dict.yaml, i.e. everything after the
+// "..." line that ends its YAML header.
+func dictBody(content []byte) ([]byte, bool) {
+	offset := 0
+	for _, line := range bytes.SplitAfter(content, []byte("\n")) {
+		offset += len(line)
+		if string(bytes.TrimSpace(line)) == "..." {
+			return content[offset:], true
+		}
+	}
+	return nil, false
+}
+
+// RenderDictYAML renders entries as a standalone Rime dictionary named name,
+// meant to be pulled into the schema's main dictionary via import_tables.
+func RenderDictYAML(name string, entries []DictEntry) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("# Rime dictionary\n")
+	buf.WriteString("# encoding: utf-8\n")
+	buf.WriteString("#\n")
+	buf.WriteString("# Generated by rime-logger suggest-dict from repeated mispredictions.\n")
+	fmt.Fprintf(&buf, "# Add \"- %s\" to import_tables of your main dictionary to use it.\n", name)
+	buf.WriteString("\n---\n")
+	fmt.Fprintf(&buf, "name: %s\n", name)
+	fmt.Fprintf(&buf, "version: %s\n", strconv.Quote(time.Now().Format("2006-01-02")))
+	buf.WriteString("sort: by_weight\n")
+	buf.WriteString("...\n\n")
+	for _, e := range entries {
+		fmt.Fprintf(&buf, "%s\t%s\t%d\n", e.Text, e.Code, e.Weight)
+	}
+	return buf.Bytes()
+}
+
+func customPhraseHeader() string {
+	return `# Rime table
+# coding: utf-8
+#@/db_name	custom_phrase.txt
+#@/db_type	tabledb
+#
+# 用户自定义词语
+# text	code	weight
+#
+`
+}
@@ -0,0 +1,151 @@
+package manager
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RimeDirEnv names the environment variable that overrides the detected
+// Rime user directory.
+const RimeDirEnv = "RIME_USER_DIR"
+
+// Frontend names
+const (
+	FrontendRime     = "rime" // ~/.config/rime, shared by several Linux tools
+	FrontendFcitx    = "fcitx-rime"
+	FrontendFcitx5   = "fcitx5-rime"
+	FrontendIBus     = "ibus-rime"
+	FrontendSquirrel = "squirrel"
+	FrontendWeasel   = "weasel"
+	FrontendTrime    = "trime"
+	FrontendHamster  = "hamster"
+)
+
+// Frontend is a Rime frontend whose directory exists on this machine.
+type Frontend struct {
+	Name      string `json:"name" yaml:"name"`
+	Directory string `json:"directory" yaml:"directory"`
+	// Sync is true for a sync folder written by another device (Trime on
+	// Android, Hamster on iOS) rather than a live user directory.
+	Sync bool `json:"sync,omitempty" yaml:"sync,omitempty"`
+}
+
+// installationInfo is the subset of installation.yaml used for detection.
+type installationInfo struct {
+	InstallationID       string `yaml:"installation_id"`
+	DistributionCodeName string `yaml:"distribution_code_name"`
+	DistributionName     string `yaml:"distribution_name"`
+	SyncDir              string `yaml:"sync_dir"`
+}
+
+// frontendCandidates returns the user directories each desktop frontend uses
+// on this OS, in order of preference.
+func frontendCandidates() []Frontend {
+	switch runtime.GOOS {
+	case "windows":
+		if appData := os.Getenv("APPDATA"); appData != "" {
+			return []Frontend{{Name: FrontendWeasel, Directory: filepath.Join(appData, "Rime")}}
+		}
+	case "darwin":
+		if homeDir, err := os.UserHomeDir(); err == nil {
+			return []Frontend{{Name: FrontendSquirrel, Directory: filepath.Join(homeDir, "Library", "Rime")}}
+		}
+	case "linux":
+		if homeDir, err := os.UserHomeDir(); err == nil {
+			return []Frontend{
+				{Name: FrontendRime, Directory: filepath.Join(homeDir, ".config", "rime")},
+				{Name: FrontendFcitx, Directory: filepath.Join(homeDir, ".config", "fcitx", "rime")},
+				{Name: FrontendFcitx5, Directory: filepath.Join(homeDir, ".config", "fcitx5", "rime")},
+				{Name: FrontendFcitx5, Directory: filepath.Join(homeDir, ".local", "share", "fcitx5", "rime")},
+				{Name: FrontendIBus, Directory: filepath.Join(homeDir, ".config", "ibus", "rime")},
+			}
+		}
+	}
+	return nil
+}
+
+// DetectFrontends lists the frontends whose user directory exists, in order
+// of preference, followed by the Trime and Hamster sync folders found in
+// their sync directories.
+func DetectFrontends() []Frontend {
+	var found []Frontend
+	seen := make(map[string]bool)
+	for _, f := range frontendCandidates() {
+		if info, err := os.Stat(f.Directory); err == nil && info.IsDir() && !seen[f.Directory] {
+			seen[f.Directory] = true
+			found = append(found, f)
+		}
+	}
+
+	live := len(found)
+	for _, f := range found[:live] {
+		for _, synced := range syncFolders(f.Directory) {
+			if !seen[synced.Directory] {
+				seen[synced.Directory] = true
+				found = append(found, synced)
+			}
+		}
+	}
+	return found
+}
+
+// syncFolders returns the mobile frontends found in the sync directory of a
+// user directory. Every device syncing there has a folder named after its
+// installation_id holding a copy of its installation.yaml.
+func syncFolders(userDir string) []Frontend {
+	syncDir := filepath.Join(userDir, "sync")
+	if info, err := readInstallation(userDir); err == nil && info.SyncDir != "" {
+		syncDir = info.SyncDir
+	}
+
+	entries, err := os.ReadDir(syncDir)
+	if err != nil {
+		return nil
+	}
+	var found []Frontend
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		dir := filepath.Join(syncDir, e.Name())
+		info, err := readInstallation(dir)
+		if err != nil {
+			continue
+		}
+		name := strings.ToLower(info.DistributionCodeName + " " + info.DistributionName)
+		switch {
+		case strings.Contains(name, FrontendTrime):
+			found = append(found, Frontend{Name: FrontendTrime, Directory: dir, Sync: true})
+		case strings.Contains(name, FrontendHamster):
+			found = append(found, Frontend{Name: FrontendHamster, Directory: dir, Sync: true})
+		}
+	}
+	return found
+}
+
+// InstallationID returns the installation_id Rime recorded in the
+// installation.yaml of a user or sync directory, or "" if there is none. It
+// identifies the device the directory belongs to.
+func InstallationID(dir string) string {
+	info, err := readInstallation(dir)
+	if err != nil {
+		return ""
+	}
+	return info.InstallationID
+}
+
+func readInstallation(dir string) (*installationInfo, error) {
+	content, err := os.ReadFile(filepath.Join(dir, "installation.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	var info installationInfo
+	if err := yaml.Unmarshal(content, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
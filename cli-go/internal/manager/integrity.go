@@ -0,0 +1,70 @@
+package manager
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ScriptState is the result of comparing an installed logger script with the
+// one embedded in the tool.
+type ScriptState string
+
+// Script states
+const (
+	ScriptMissing  ScriptState = "missing"
+	ScriptCurrent  ScriptState = "current"
+	ScriptOutdated ScriptState = "outdated" // an older release, unmodified or not
+	ScriptModified ScriptState = "modified" // same or unknown version, different content
+)
+
+// ScriptIntegrity describes the installed input_habit_logger.lua.
+type ScriptIntegrity struct {
+	State          ScriptState `json:"state" yaml:"state"`
+	Path           string      `json:"path" yaml:"path"`
+	SHA256         string      `json:"sha256,omitempty" yaml:"sha256,omitempty"`
+	ExpectedSHA256 string      `json:"expected_sha256" yaml:"expected_sha256"`
+	// Version is the installed version header, empty if it has none.
+	Version string `json:"version,omitempty" yaml:"version,omitempty"`
+}
+
+// CheckLoggerIntegrity hashes the installed logger script and compares it with
+// pristine, the script embedded in the tool. A script whose content differs
+// is reported as outdated when its version header is older than pristine's,
+// and as modified otherwise.
+func (m *RimeManager) CheckLoggerIntegrity(pristine []byte) (*ScriptIntegrity, error) {
+	result := &ScriptIntegrity{
+		Path:           filepath.Join(m.LuaDirectory, LoggerLuaFile),
+		ExpectedSHA256: sha256Hex(pristine),
+	}
+
+	content, err := os.ReadFile(result.Path)
+	if os.IsNotExist(err) {
+		result.State = ScriptMissing
+		return result, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", result.Path, err)
+	}
+
+	result.SHA256 = sha256Hex(content)
+	installed, versionErr := ParseLoggerVersion(content)
+	if versionErr == nil {
+		result.Version = installed.String()
+	}
+
+	switch {
+	case result.SHA256 == result.ExpectedSHA256:
+		result.State = ScriptCurrent
+	case versionErr != nil:
+		result.State = ScriptModified
+	default:
+		expected, err := ParseLoggerVersion(pristine)
+		if err == nil && installed.Compare(expected) < 0 {
+			result.State = ScriptOutdated
+		} else {
+			result.State = ScriptModified
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,188 @@
+package manager
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// LoggerConfig is the effective configuration of the Lua logger: the table
+// returned by input_habit_logger_config.lua merged into the logger script's
+// built-in defaults, exactly as input_habit_logger.lua does.
+type LoggerConfig struct {
+	// PresetChoice is the value of preset_choice in the config file.
+	PresetChoice string `json:"preset_choice,omitempty" yaml:"preset_choice,omitempty"`
+	// Preset is the preset table in effect. It differs from PresetChoice when
+	// the choice is unknown and the config falls back to "custom".
+	Preset                string `json:"preset,omitempty" yaml:"preset,omitempty"`
+	Enabled               bool   `json:"enabled" yaml:"enabled"`
+	LogOnlyNonFirstChoice bool   `json:"log_only_non_first_choice" yaml:"log_only_non_first_choice"`
+	// LogFilePath is empty when the logger writes to its default location.
+	LogFilePath string                     `json:"log_file_path,omitempty" yaml:"log_file_path,omitempty"`
+	LogEvents   map[string]bool            `json:"log_events" yaml:"log_events"`
+	LogFields   map[string]map[string]bool `json:"log_fields" yaml:"log_fields"`
+	// EventSubtypes holds log_fields.input_state_changed.event_subtype, the
+	// input_state_changed subtypes that are logged.
+	EventSubtypes map[string]bool `json:"event_subtypes,omitempty" yaml:"event_subtypes,omitempty"`
+}
+
+// defaultLoggerConfig returns the defaults at the top of input_habit_logger.lua.
+func defaultLoggerConfig() *luaTable {
+	events := newLuaTable()
+	for _, e := range []struct {
+		name string
+		on   bool
+	}{
+		{"session_start", true},
+		{"session_end", true},
+		{"text_committed", true},
+		{"input_state_changed", false},
+		{"error", true},
+	} {
+		events.set(e.name, e.on)
+	}
+
+	stateFields := newLuaTable()
+	stateFields.set("event_subtype", newLuaTable())
+	fields := newLuaTable()
+	fields.set("text_committed", newLuaTable())
+	fields.set("input_state_changed", stateFields)
+
+	config := newLuaTable()
+	config.set("enabled", true)
+	config.set("log_only_non_first_choice", false)
+	config.set("log_events", events)
+	config.set("log_fields", fields)
+	return config
+}
+
+// mergeLuaTables merges src into dst like the logger script's merge():
+// tables present on both sides are merged recursively, anything else replaces.
+func mergeLuaTables(dst, src *luaTable) {
+	for _, k := range src.keys {
+		v := src.fields[k]
+		if vt, ok := v.(*luaTable); ok {
+			if dt, ok := dst.get(k).(*luaTable); ok {
+				mergeLuaTables(dt, vt)
+				continue
+			}
+		}
+		dst.set(k, v)
+	}
+}
+
+// DefaultLoggerConfig returns the configuration the logger uses when no
+// config file is installed or the config file fails to load.
+func DefaultLoggerConfig() *LoggerConfig {
+	return newLoggerConfig(defaultLoggerConfig())
+}
+
+// ParseLoggerConfig evaluates the source of input_habit_logger_config.lua in
+// a sandbox (no function calls) and returns the effective configuration.
+// As in the logger, a config that does not return a table leaves the defaults.
+func ParseLoggerConfig(src []byte) (*LoggerConfig, error) {
+	chunk, err := parseLua(src)
+	if err != nil {
+		return nil, err
+	}
+	returned, env, err := evalLua(chunk)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := defaultLoggerConfig()
+	userConfig, ok := returned.(*luaTable)
+	if ok {
+		mergeLuaTables(merged, userConfig)
+	}
+	config := newLoggerConfig(merged)
+
+	if choice, ok := env.vars["preset_choice"].(string); ok {
+		config.PresetChoice = choice
+	}
+	if presets, ok := env.vars["presets"].(*luaTable); ok && userConfig != nil {
+		for _, k := range presets.keys {
+			if name, ok := k.(string); ok && presets.fields[k] == userConfig {
+				config.Preset = name
+				break
+			}
+		}
+	}
+	return config, nil
+}
+
+func newLoggerConfig(t *luaTable) *LoggerConfig {
+	config := &LoggerConfig{
+		Enabled:               luaTruthy(t.get("enabled")),
+		LogOnlyNonFirstChoice: luaTruthy(t.get("log_only_non_first_choice")),
+		LogEvents:             make(map[string]bool),
+		LogFields:             make(map[string]map[string]bool),
+	}
+	if path, ok := t.get("log_file_path").(string); ok {
+		config.LogFilePath = path
+	}
+
+	if events, ok := t.get("log_events").(*luaTable); ok {
+		for _, k := range events.keys {
+			if name, ok := k.(string); ok {
+				config.LogEvents[name] = luaTruthy(events.fields[k])
+			}
+		}
+	}
+
+	fields, _ := t.get("log_fields").(*luaTable)
+	if fields == nil {
+		return config
+	}
+	for _, k := range fields.keys {
+		event, ok := k.(string)
+		rules, isTable := fields.fields[k].(*luaTable)
+		if !ok || !isTable {
+			continue
+		}
+		config.LogFields[event] = make(map[string]bool)
+		for _, fk := range rules.keys {
+			field, ok := fk.(string)
+			if !ok {
+				continue
+			}
+			if subtypes, ok := rules.fields[fk].(*luaTable); ok && field == "event_subtype" {
+				config.EventSubtypes = make(map[string]bool)
+				for _, sk := range subtypes.keys {
+					if name, ok := sk.(string); ok {
+						config.EventSubtypes[name] = luaTruthy(subtypes.fields[sk])
+					}
+				}
+				continue
+			}
+			// The logger only copies fields whose rule is the boolean true
+			on, _ := rules.fields[fk].(bool)
+			config.LogFields[event][field] = on
+		}
+	}
+	return config
+}
+
+// GetConfigPath returns the path to the installed input_habit_logger_config.lua.
+func (m *RimeManager) GetConfigPath() string {
+	return filepath.Join(m.LuaDirectory, ConfigLuaFile)
+}
+
+// LoadLoggerConfig reads the effective configuration of the installed logger.
+// Without a config file the logger's defaults apply. If the file cannot be
+// evaluated, the error is returned together with the defaults, which is what
+// the logger itself falls back to.
+func (m *RimeManager) LoadLoggerConfig() (*LoggerConfig, error) {
+	content, err := os.ReadFile(m.GetConfigPath())
+	if os.IsNotExist(err) {
+		return DefaultLoggerConfig(), nil
+	}
+	if err != nil {
+		return DefaultLoggerConfig(), fmt.Errorf("could not read %s: %w", ConfigLuaFile, err)
+	}
+	config, err := ParseLoggerConfig(content)
+	if err != nil {
+		return DefaultLoggerConfig(), fmt.Errorf("%s: %w", ConfigLuaFile, err)
+	}
+	return config, nil
+}
@@ -0,0 +1,189 @@
+package manager
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// luaSetting is a value written in a config file, found by walking its
+// assignments and the table constructors they contain. path is the chain of
+// names leading to it, e.g. presets.custom.log_events.error.
+type luaSetting struct {
+	path []string
+	expr *luaExpr
+}
+
+func (s luaSetting) key() string {
+	return strings.Join(s.path, ".")
+}
+
+// isTable reports whether the setting is written as a table constructor.
+func (s luaSetting) isTable() bool {
+	return s.expr.kind == luaExprTable
+}
+
+// settings lists every setting of the chunk in source order, tables before
+// their fields. Both "presets = { custom = { ... } }" and
+// "presets.custom.enabled = false" produce a setting. Fields without a
+// string key are skipped.
+func (c *luaChunk) settings() []luaSetting {
+	var list []luaSetting
+	var walk func(path []string, e *luaExpr)
+	walk = func(path []string, e *luaExpr) {
+		list = append(list, luaSetting{path: path, expr: e})
+		if e.kind != luaExprTable {
+			return
+		}
+		for _, f := range e.fields {
+			if f.name != "" {
+				walk(append(append([]string(nil), path...), f.name), f.value)
+			}
+		}
+	}
+
+	for _, stmt := range c.stmts {
+		for i, target := range stmt.targets {
+			if i >= len(stmt.values) {
+				break
+			}
+			if path, ok := luaTargetPath(target); ok {
+				walk(path, stmt.values[i])
+			}
+		}
+	}
+	return list
+}
+
+// effectiveSettings returns the settings still in effect at the end of the
+// chunk, in source order: only the last assignment of each path is kept, and
+// assignments inside a table that is assigned again later are dropped.
+func (c *luaChunk) effectiveSettings() []luaSetting {
+	var list []luaSetting
+	for _, s := range c.settings() {
+		key := s.key()
+		list = slices.DeleteFunc(list, func(prev luaSetting) bool {
+			k := prev.key()
+			return k == key || strings.HasPrefix(k, key+".")
+		})
+		list = append(list, s)
+	}
+	return list
+}
+
+// luaTargetPath returns the name chain of an assignment target such as
+// presets.custom["log_events"], or false if it uses a non-constant key.
+func luaTargetPath(e *luaExpr) ([]string, bool) {
+	switch e.kind {
+	case luaExprName:
+		return []string{e.name}, true
+	case luaExprIndex:
+		key, ok := e.right.value.(string)
+		if !ok || e.right.kind != luaExprString {
+			return nil, false
+		}
+		path, ok := luaTargetPath(e.left)
+		return append(path, key), ok
+	default:
+		return nil, false
+	}
+}
+
+// setting returns the last setting written at path; later assignments win.
+func (c *luaChunk) setting(path []string) (luaSetting, bool) {
+	key := strings.Join(path, ".")
+	var found luaSetting
+	ok := false
+	for _, s := range c.settings() {
+		if s.key() == key {
+			found, ok = s, true
+		}
+	}
+	return found, ok
+}
+
+// text returns the source text of an expression.
+func (c *luaChunk) text(e *luaExpr) string {
+	return c.src[e.start:e.end]
+}
+
+// luaEdit replaces src[start:end] with text. start == end inserts.
+type luaEdit struct {
+	start, end int
+	text       string
+}
+
+// applyLuaEdits applies non-overlapping edits to src. Insertions at the same
+// offset keep the order they were given in. Overlapping edits are an error.
+func applyLuaEdits(src string, edits []luaEdit) (string, error) {
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].start < edits[j].start })
+	var b strings.Builder
+	last := 0
+	for _, e := range edits {
+		if e.start < last {
+			return "", fmt.Errorf("overlapping edits at offset %d", e.start)
+		}
+		b.WriteString(src[last:e.start])
+		b.WriteString(e.text)
+		last = e.end
+	}
+	b.WriteString(src[last:])
+	return b.String(), nil
+}
+
+// replaceEdit replaces the value of a setting with a Lua literal.
+func replaceEdit(s luaSetting, literal string) luaEdit {
+	return luaEdit{start: s.expr.start, end: s.expr.end, text: literal}
+}
+
+// insertFieldEdit adds "name = literal" to a table constructor, matching its
+// layout: inline when the first field shares the line of "{", else on a new
+// line indented like the existing fields.
+func (c *luaChunk) insertFieldEdit(table *luaExpr, name, literal string) luaEdit {
+	field := luaFieldKey(name) + " = " + literal
+	openEnd := table.start + 1
+	lineEnd := strings.IndexByte(c.src[openEnd:], '\n')
+
+	switch {
+	case lineEnd < 0 || openEnd+lineEnd >= table.end:
+		// The whole table is on one line
+		if len(table.fields) == 0 {
+			return luaEdit{start: table.start, end: table.end, text: "{ " + field + " }"}
+		}
+		return luaEdit{start: table.fields[0].start, end: table.fields[0].start, text: field + ", "}
+	case len(table.fields) > 0 && table.fields[0].start < openEnd+lineEnd:
+		return luaEdit{start: table.fields[0].start, end: table.fields[0].start, text: field + ", "}
+	}
+
+	insertAt := openEnd + lineEnd + 1
+	eol := "\n"
+	if lineEnd > 0 && c.src[openEnd+lineEnd-1] == '\r' {
+		eol = "\r\n"
+	}
+	indent := c.lineIndent(table.start) + "    "
+	if len(table.fields) > 0 {
+		indent = c.lineIndent(table.fields[0].start)
+	}
+	return luaEdit{start: insertAt, end: insertAt, text: indent + field + "," + eol}
+}
+
+// lineIndent returns the leading whitespace of the line containing pos.
+func (c *luaChunk) lineIndent(pos int) string {
+	lineStart := strings.LastIndexByte(c.src[:pos], '\n') + 1
+	line := c.src[lineStart:]
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
+
+// luaFieldKey formats a table key, using the short form for plain names.
+func luaFieldKey(name string) string {
+	if name == "" || luaKeywords[name] || !isLuaNameStart(name[0]) {
+		return "[" + quoteLuaString(name) + "]"
+	}
+	for i := 1; i < len(name); i++ {
+		if !isLuaNameStart(name[i]) && !isLuaDigit(name[i]) {
+			return "[" + quoteLuaString(name) + "]"
+		}
+	}
+	return name
+}
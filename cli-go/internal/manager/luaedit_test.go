@@ -0,0 +1,230 @@
+package manager
+
+import (
+	"strings"
+	"testing"
+
+	"rime-wanxiang-logger-go/internal/assets"
+)
+
+// editConfig is a config using the syntax SetConfigValue must preserve.
+const editConfig = `-- 日志配置
+local preset_choice = 'custom' -- 当前预设
+
+local presets = {
+    normal = { enabled = true },
+    custom = {
+        enabled = true, -- 总开关
+        --[[ 日志路径
+             nil 表示默认位置 ]]
+        log_file_path = nil,
+        log_events = {
+            text_committed = true, -- 上屏
+        },
+    },
+}
+
+return presets[preset_choice] or presets.custom
+`
+
+func TestSetConfigValue(t *testing.T) {
+	tests := []struct {
+		name   string
+		key    string
+		value  string
+		preset string
+		// want are lines that must appear in the result
+		want []string
+		// check verifies the value through the evaluator
+		check func(*LoggerConfig) bool
+	}{
+		{
+			name:  "replace a boolean",
+			key:   "enabled",
+			value: "false",
+			want:  []string{"        enabled = false, -- 总开关"},
+			check: func(c *LoggerConfig) bool { return !c.Enabled },
+		},
+		{
+			name:  "replace nil with a path",
+			key:   "log_file_path",
+			value: `C:\Users\me\log.jsonl`,
+			want:  []string{`        log_file_path = "C:\\Users\\me\\log.jsonl",`},
+			check: func(c *LoggerConfig) bool { return c.LogFilePath == `C:\Users\me\log.jsonl` },
+		},
+		{
+			name:  "nested value",
+			key:   "log_events.text_committed",
+			value: "false",
+			want:  []string{"            text_committed = false, -- 上屏"},
+			check: func(c *LoggerConfig) bool { return !c.LogEvents["text_committed"] },
+		},
+		{
+			name:  "insert a missing field",
+			key:   "log_events.error",
+			value: "false",
+			want:  []string{"error = false"},
+			check: func(c *LoggerConfig) bool { return !c.LogEvents["error"] && c.LogEvents["text_committed"] },
+		},
+		{
+			name:  "insert a missing table",
+			key:   "log_fields.text_committed.committed_text",
+			value: "false",
+			want:  []string{"log_fields = { text_committed = { committed_text = false } }"},
+			check: func(c *LoggerConfig) bool {
+				v, ok := c.LogFields["text_committed"]["committed_text"]
+				return ok && !v
+			},
+		},
+		{
+			name:   "another preset",
+			key:    "enabled",
+			value:  "false",
+			preset: "normal",
+			want:   []string{"    normal = { enabled = false },"},
+			check:  func(c *LoggerConfig) bool { return c.Enabled }, // custom is still in effect
+		},
+		{
+			name:  "preset choice keeps the comment",
+			key:   "preset",
+			value: "normal",
+			want:  []string{`local preset_choice = "normal" -- 当前预设`},
+			check: func(c *LoggerConfig) bool { return c.Preset == "normal" },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated, change, err := SetConfigValue([]byte(editConfig), tt.key, tt.value, tt.preset)
+			if err != nil {
+				t.Fatalf("SetConfigValue: %v", err)
+			}
+			if change.New == "" {
+				t.Error("change.New is empty")
+			}
+			got := string(updated)
+			for _, line := range tt.want {
+				if !strings.Contains(got, line) {
+					t.Errorf("result does not contain %q:\n%s", line, got)
+				}
+			}
+			assertSameComments(t, editConfig, got)
+
+			config, err := ParseLoggerConfig(updated)
+			if err != nil {
+				t.Fatalf("result does not evaluate: %v\n%s", err, got)
+			}
+			if !tt.check(config) {
+				t.Errorf("value not in effect: %+v\n%s", config, got)
+			}
+		})
+	}
+}
+
+func TestSetConfigValueRoundTrip(t *testing.T) {
+	src := []byte(editConfig)
+	for _, edit := range []struct{ key, value string }{
+		{"enabled", "false"},
+		{"log_file_path", "/tmp/log.jsonl"},
+		{"enabled", "true"},
+		{"log_file_path", "nil"},
+	} {
+		var err error
+		src, _, err = SetConfigValue(src, edit.key, edit.value, "")
+		if err != nil {
+			t.Fatalf("set %s=%s: %v", edit.key, edit.value, err)
+		}
+	}
+	if string(src) != editConfig {
+		t.Errorf("setting values back did not restore the file:\n%s", src)
+	}
+}
+
+func TestSetConfigValueTemplate(t *testing.T) {
+	updated, _, err := SetConfigValue(assets.ConfigScript, "log_events.input_state_changed", "true", "normal")
+	if err != nil {
+		t.Fatalf("SetConfigValue: %v", err)
+	}
+	assertSameComments(t, string(assets.ConfigScript), string(updated))
+
+	config, err := ParseLoggerConfig(updated)
+	if err != nil {
+		t.Fatalf("ParseLoggerConfig: %v", err)
+	}
+	if !config.LogEvents["input_state_changed"] {
+		t.Error("input_state_changed was not enabled")
+	}
+	if diff := len(updated) - len(assets.ConfigScript); diff != len("true")-len("false") {
+		t.Errorf("expected only the value to change, length changed by %d", diff)
+	}
+}
+
+func TestSetConfigValueErrors(t *testing.T) {
+	overridden := editConfig + "\npresets.custom.enabled = true\n"
+	tests := []struct {
+		name   string
+		src    string
+		key    string
+		value  string
+		preset string
+	}{
+		{"unknown key", editConfig, "log_events.nonsense", "true", ""},
+		{"not a boolean", editConfig, "enabled", "maybe", ""},
+		{"unknown preset", editConfig, "enabled", "false", "verbose"},
+		{"unknown preset choice", editConfig, "preset", "verbose", ""},
+		{"a table", editConfig, "log_events", "false", ""},
+		{"overridden later", overridden, "enabled", "false", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if updated, _, err := SetConfigValue([]byte(tt.src), tt.key, tt.value, tt.preset); err == nil {
+				t.Errorf("expected an error, got:\n%s", updated)
+			}
+		})
+	}
+}
+
+func TestParseConfigKey(t *testing.T) {
+	for _, key := range []string{
+		"enabled",
+		"log_events.input_state_changed",
+		"log_fields.text_committed.source_event_timestamp",
+		"log_fields.error.key_repr",
+		"log_fields.input_state_changed.event_subtype.menu_navigation",
+	} {
+		if _, err := ParseConfigKey(key); err != nil {
+			t.Errorf("ParseConfigKey(%q): %v", key, err)
+		}
+	}
+	for _, key := range []string{"log_fields.text_committed.nonsense", "log_events.keypress", "log_fields.error"} {
+		if _, err := ParseConfigKey(key); err == nil {
+			t.Errorf("ParseConfigKey(%q): expected an error", key)
+		}
+	}
+}
+
+// assertSameComments checks that every comment line of before is still in after.
+func assertSameComments(t *testing.T, before, after string) {
+	t.Helper()
+	for _, line := range strings.Split(before, "\n") {
+		if i := strings.Index(line, "--"); i >= 0 && !strings.Contains(after, line[i:]) {
+			t.Errorf("comment %q was lost", line[i:])
+		}
+	}
+}
+
+func TestApplyLuaEdits(t *testing.T) {
+	got, err := applyLuaEdits("a = 1, b = 2", []luaEdit{
+		{start: 11, end: 12, text: "3"},
+		{start: 4, end: 5, text: "x"},
+		{start: 0, end: 0, text: "c = 0, "},
+	})
+	if err != nil || got != "c = 0, a = x, b = 3" {
+		t.Errorf("got %q, %v", got, err)
+	}
+
+	if _, err := applyLuaEdits("a = 1", []luaEdit{{start: 4, end: 5, text: "2"}, {start: 4, end: 5, text: "3"}}); err == nil {
+		t.Error("expected an error for overlapping edits")
+	}
+}
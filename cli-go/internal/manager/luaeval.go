@@ -0,0 +1,256 @@
+package manager
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// luaValue is an evaluated Lua value: nil, bool, float64, string or *luaTable.
+type luaValue any
+
+// luaTable is a Lua table that remembers the order its keys were first set in.
+type luaTable struct {
+	keys   []luaValue
+	fields map[luaValue]luaValue
+}
+
+func newLuaTable() *luaTable {
+	return &luaTable{fields: make(map[luaValue]luaValue)}
+}
+
+func (t *luaTable) get(key luaValue) luaValue {
+	return t.fields[key]
+}
+
+func (t *luaTable) set(key, value luaValue) {
+	if value == nil {
+		if _, ok := t.fields[key]; ok {
+			delete(t.fields, key)
+			for i, k := range t.keys {
+				if k == key {
+					t.keys = append(t.keys[:i], t.keys[i+1:]...)
+					break
+				}
+			}
+		}
+		return
+	}
+	if _, ok := t.fields[key]; !ok {
+		t.keys = append(t.keys, key)
+	}
+	t.fields[key] = value
+}
+
+// luaTruthy reports whether v counts as true in a Lua condition.
+func luaTruthy(v luaValue) bool {
+	b, isBool := v.(bool)
+	return v != nil && (!isBool || b)
+}
+
+func luaTypeName(v luaValue) string {
+	switch v.(type) {
+	case nil:
+		return "nil"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	default:
+		return "table"
+	}
+}
+
+// luaEnv evaluates a chunk. Locals and globals share one scope, which is
+// enough for a config file without blocks or functions.
+type luaEnv struct {
+	chunk *luaChunk
+	vars  map[string]luaValue
+}
+
+// evalLua runs chunk and returns the value of its return statement.
+func evalLua(chunk *luaChunk) (luaValue, *luaEnv, error) {
+	env := &luaEnv{chunk: chunk, vars: make(map[string]luaValue)}
+	for _, stmt := range chunk.stmts {
+		values := make([]luaValue, len(stmt.values))
+		for i, e := range stmt.values {
+			v, err := env.eval(e)
+			if err != nil {
+				return nil, env, err
+			}
+			values[i] = v
+		}
+		if stmt.ret {
+			if len(values) == 0 {
+				return nil, env, nil
+			}
+			return values[0], env, nil
+		}
+
+		for i, target := range stmt.targets {
+			var v luaValue
+			if i < len(values) {
+				v = values[i]
+			}
+			if err := env.assign(target, v); err != nil {
+				return nil, env, err
+			}
+		}
+	}
+	return nil, env, nil
+}
+
+func (env *luaEnv) errorf(e *luaExpr, format string, a ...any) error {
+	lex := &luaLexer{src: env.chunk.src}
+	return lex.errorf(e.start, format, a...)
+}
+
+func (env *luaEnv) assign(target *luaExpr, v luaValue) error {
+	if target.kind == luaExprName {
+		env.vars[target.name] = v
+		return nil
+	}
+	t, key, err := env.indexTarget(target)
+	if err != nil {
+		return err
+	}
+	if key == nil {
+		return env.errorf(target.right, "table index is nil")
+	}
+	t.set(key, v)
+	return nil
+}
+
+// indexTarget evaluates the table and key of an index expression. The key
+// may be nil, which reads as nil but cannot be assigned to.
+func (env *luaEnv) indexTarget(e *luaExpr) (*luaTable, luaValue, error) {
+	left, err := env.eval(e.left)
+	if err != nil {
+		return nil, nil, err
+	}
+	t, ok := left.(*luaTable)
+	if !ok {
+		return nil, nil, env.errorf(e, "attempt to index a %s value", luaTypeName(left))
+	}
+	key, err := env.eval(e.right)
+	if err != nil {
+		return nil, nil, err
+	}
+	return t, normalizeLuaKey(key), nil
+}
+
+// normalizeLuaKey maps float keys with an integer value to one key, as Lua does.
+func normalizeLuaKey(key luaValue) luaValue {
+	if f, ok := key.(float64); ok && f == math.Trunc(f) {
+		return f + 0 // turns -0 into 0
+	}
+	return key
+}
+
+func (env *luaEnv) eval(e *luaExpr) (luaValue, error) {
+	switch e.kind {
+	case luaExprNil:
+		return nil, nil
+	case luaExprBool, luaExprNumber, luaExprString:
+		return e.value, nil
+	case luaExprName:
+		return env.vars[e.name], nil
+	case luaExprParen:
+		return env.eval(e.left)
+	case luaExprIndex:
+		t, key, err := env.indexTarget(e)
+		if err != nil {
+			return nil, err
+		}
+		return t.get(key), nil
+	case luaExprNot:
+		v, err := env.eval(e.left)
+		return !luaTruthy(v), err
+	case luaExprNeg:
+		v, err := env.eval(e.left)
+		if err != nil {
+			return nil, err
+		}
+		f, ok := v.(float64)
+		if !ok {
+			return nil, env.errorf(e, "attempt to perform arithmetic on a %s value", luaTypeName(v))
+		}
+		return -f, nil
+	case luaExprAnd, luaExprOr:
+		left, err := env.eval(e.left)
+		if err != nil {
+			return nil, err
+		}
+		if luaTruthy(left) == (e.kind == luaExprOr) {
+			return left, nil
+		}
+		return env.eval(e.right)
+	case luaExprTable:
+		t := newLuaTable()
+		next := 1.0
+		for _, f := range e.fields {
+			v, err := env.eval(f.value)
+			if err != nil {
+				return nil, err
+			}
+			if f.key == nil {
+				t.set(next, v)
+				next++
+				continue
+			}
+			key, err := env.eval(f.key)
+			if err != nil {
+				return nil, err
+			}
+			if key == nil {
+				return nil, env.errorf(f.key, "table index is nil")
+			}
+			t.set(normalizeLuaKey(key), v)
+		}
+		return t, nil
+	default:
+		return nil, env.errorf(e, "unsupported expression")
+	}
+}
+
+// formatLuaValue renders a scalar value as a Lua literal.
+func formatLuaValue(v luaValue) string {
+	switch v := v.(type) {
+	case nil:
+		return "nil"
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case string:
+		return quoteLuaString(v)
+	default:
+		return fmt.Sprintf("<%s>", luaTypeName(v))
+	}
+}
+
+// quoteLuaString returns s as a double-quoted Lua string literal.
+func quoteLuaString(s string) string {
+	b := []byte{'"'}
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"', '\\':
+			b = append(b, '\\', c)
+		case '\n':
+			b = append(b, '\\', 'n')
+		case '\r':
+			b = append(b, '\\', 'r')
+		case '\t':
+			b = append(b, '\\', 't')
+		default:
+			if c < 0x20 || c == 0x7f {
+				b = append(b, fmt.Sprintf("\\%03d", c)...)
+			} else {
+				b = append(b, c)
+			}
+		}
+	}
+	return string(append(b, '"'))
+}
@@ -0,0 +1,761 @@
+package manager
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// This file implements the subset of Lua used by input_habit_logger_config.lua:
+// local and global assignments, a final return, and expressions made of
+// literals (nil, booleans, numbers, all string forms), table constructors,
+// variables, indexing, "and", "or" and "not". Function calls and every other
+// construct are rejected, so evaluating a config can never run code.
+
+type luaTokenKind int
+
+const (
+	luaEOF luaTokenKind = iota
+	luaName
+	luaKeyword
+	luaNumber
+	luaString
+	luaPunct
+)
+
+type luaToken struct {
+	kind  luaTokenKind
+	text  string  // name, keyword or punctuator
+	str   string  // decoded value of a string literal
+	num   float64 // value of a number literal
+	start int     // byte offsets in the source
+	end   int
+}
+
+var luaKeywords = map[string]bool{
+	"and": true, "break": true, "do": true, "else": true, "elseif": true, "end": true,
+	"false": true, "for": true, "function": true, "goto": true, "if": true, "in": true,
+	"local": true, "nil": true, "not": true, "or": true, "repeat": true, "return": true,
+	"then": true, "true": true, "until": true, "while": true,
+}
+
+// luaSyntaxError is a parse error with the 1-based line it occurred on.
+type luaSyntaxError struct {
+	line int
+	msg  string
+}
+
+func (e *luaSyntaxError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.line, e.msg)
+}
+
+type luaLexer struct {
+	src string
+	pos int
+}
+
+func (l *luaLexer) errorf(pos int, format string, a ...any) error {
+	return &luaSyntaxError{line: strings.Count(l.src[:pos], "\n") + 1, msg: fmt.Sprintf(format, a...)}
+}
+
+// longBracket returns the level of a long bracket "[==[" at pos, or -1.
+func (l *luaLexer) longBracket(pos int) int {
+	if pos >= len(l.src) || l.src[pos] != '[' {
+		return -1
+	}
+	level := 0
+	for i := pos + 1; i < len(l.src); i++ {
+		switch l.src[i] {
+		case '=':
+			level++
+		case '[':
+			return level
+		default:
+			return -1
+		}
+	}
+	return -1
+}
+
+// readLong reads a long string or comment whose opening bracket of the given
+// level starts at l.pos, and returns its content.
+func (l *luaLexer) readLong(level int) (string, error) {
+	start := l.pos
+	l.pos += level + 2
+	closing := "]" + strings.Repeat("=", level) + "]"
+	end := strings.Index(l.src[l.pos:], closing)
+	if end < 0 {
+		return "", l.errorf(start, "unfinished long string or comment")
+	}
+	content := l.src[l.pos : l.pos+end]
+	l.pos += end + len(closing)
+	// A newline directly after the opening bracket is skipped
+	if strings.HasPrefix(content, "\r\n") {
+		content = content[2:]
+	} else if strings.HasPrefix(content, "\n") {
+		content = content[1:]
+	}
+	return content, nil
+}
+
+// skipSpace skips whitespace and comments.
+func (l *luaLexer) skipSpace() error {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == '\v':
+			l.pos++
+		case strings.HasPrefix(l.src[l.pos:], "--"):
+			l.pos += 2
+			if level := l.longBracket(l.pos); level >= 0 {
+				if _, err := l.readLong(level); err != nil {
+					return err
+				}
+				continue
+			}
+			if nl := strings.IndexByte(l.src[l.pos:], '\n'); nl >= 0 {
+				l.pos += nl + 1
+			} else {
+				l.pos = len(l.src)
+			}
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+func isLuaNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isLuaDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func (l *luaLexer) next() (luaToken, error) {
+	if err := l.skipSpace(); err != nil {
+		return luaToken{}, err
+	}
+	start := l.pos
+	if l.pos >= len(l.src) {
+		return luaToken{kind: luaEOF, start: start, end: start}, nil
+	}
+
+	c := l.src[l.pos]
+	switch {
+	case isLuaNameStart(c):
+		for l.pos < len(l.src) && (isLuaNameStart(l.src[l.pos]) || isLuaDigit(l.src[l.pos])) {
+			l.pos++
+		}
+		text := l.src[start:l.pos]
+		kind := luaName
+		if luaKeywords[text] {
+			kind = luaKeyword
+		}
+		return luaToken{kind: kind, text: text, start: start, end: l.pos}, nil
+
+	case isLuaDigit(c) || (c == '.' && l.pos+1 < len(l.src) && isLuaDigit(l.src[l.pos+1])):
+		return l.number()
+
+	case c == '"' || c == '\'':
+		s, err := l.quoted(c)
+		if err != nil {
+			return luaToken{}, err
+		}
+		return luaToken{kind: luaString, str: s, start: start, end: l.pos}, nil
+
+	case c == '[':
+		if level := l.longBracket(l.pos); level >= 0 {
+			s, err := l.readLong(level)
+			if err != nil {
+				return luaToken{}, err
+			}
+			return luaToken{kind: luaString, str: s, start: start, end: l.pos}, nil
+		}
+	}
+
+	for _, p := range []string{"==", "~=", "<=", ">=", "..", "::"} {
+		if strings.HasPrefix(l.src[l.pos:], p) {
+			l.pos += len(p)
+			return luaToken{kind: luaPunct, text: p, start: start, end: l.pos}, nil
+		}
+	}
+	if strings.IndexByte("=(){}[];:,.+-*/%^#<>~&|", c) >= 0 {
+		l.pos++
+		return luaToken{kind: luaPunct, text: string(c), start: start, end: l.pos}, nil
+	}
+	r, _ := utf8.DecodeRuneInString(l.src[l.pos:])
+	return luaToken{}, l.errorf(start, "unexpected character %q", r)
+}
+
+func (l *luaLexer) number() (luaToken, error) {
+	start := l.pos
+	hex := strings.HasPrefix(l.src[l.pos:], "0x") || strings.HasPrefix(l.src[l.pos:], "0X")
+	if hex {
+		l.pos += 2
+	}
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		exp := (!hex && (c == 'e' || c == 'E')) || (hex && (c == 'p' || c == 'P'))
+		switch {
+		case exp:
+			l.pos++
+			if l.pos < len(l.src) && (l.src[l.pos] == '+' || l.src[l.pos] == '-') {
+				l.pos++
+			}
+		case isLuaDigit(c) || c == '.' || (hex && strings.IndexByte("abcdefABCDEF", c) >= 0):
+			l.pos++
+		default:
+			goto done
+		}
+	}
+done:
+	text := l.src[start:l.pos]
+	var num float64
+	var err error
+	if hex && !strings.ContainsAny(text, ".pP") {
+		var n uint64
+		n, err = strconv.ParseUint(text[2:], 16, 64)
+		num = float64(n)
+	} else {
+		if hex && !strings.ContainsAny(text, "pP") {
+			text += "p0"
+		}
+		num, err = strconv.ParseFloat(text, 64)
+	}
+	if err != nil {
+		return luaToken{}, l.errorf(start, "malformed number %q", l.src[start:l.pos])
+	}
+	return luaToken{kind: luaNumber, num: num, start: start, end: l.pos}, nil
+}
+
+// quoted reads a '...' or "..." string and decodes its escape sequences.
+func (l *luaLexer) quoted(quote byte) (string, error) {
+	start := l.pos
+	l.pos++
+	var b strings.Builder
+	for {
+		if l.pos >= len(l.src) || l.src[l.pos] == '\n' {
+			return "", l.errorf(start, "unfinished string")
+		}
+		c := l.src[l.pos]
+		if c == quote {
+			l.pos++
+			return b.String(), nil
+		}
+		if c != '\\' {
+			b.WriteByte(c)
+			l.pos++
+			continue
+		}
+
+		l.pos++
+		if l.pos >= len(l.src) {
+			return "", l.errorf(start, "unfinished string")
+		}
+		e := l.src[l.pos]
+		l.pos++
+		switch e {
+		case 'a':
+			b.WriteByte('\a')
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'n', '\n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'v':
+			b.WriteByte('\v')
+		case '\\', '"', '\'':
+			b.WriteByte(e)
+		case 'z':
+			for l.pos < len(l.src) && strings.IndexByte(" \t\r\n\f\v", l.src[l.pos]) >= 0 {
+				l.pos++
+			}
+		case 'x':
+			if l.pos+2 > len(l.src) {
+				return "", l.errorf(start, "invalid \\x escape")
+			}
+			n, err := strconv.ParseUint(l.src[l.pos:l.pos+2], 16, 8)
+			if err != nil {
+				return "", l.errorf(start, "invalid \\x escape")
+			}
+			b.WriteByte(byte(n))
+			l.pos += 2
+		case 'u':
+			end := strings.IndexByte(l.src[l.pos:], '}')
+			if !strings.HasPrefix(l.src[l.pos:], "{") || end < 0 {
+				return "", l.errorf(start, "invalid \\u escape")
+			}
+			n, err := strconv.ParseUint(l.src[l.pos+1:l.pos+end], 16, 32)
+			if err != nil {
+				return "", l.errorf(start, "invalid \\u escape")
+			}
+			b.WriteRune(rune(n))
+			l.pos += end + 1
+		default:
+			if !isLuaDigit(e) {
+				return "", l.errorf(start, "invalid escape sequence '\\%c'", e)
+			}
+			digits := l.pos - 1
+			for l.pos < len(l.src) && l.pos-digits < 3 && isLuaDigit(l.src[l.pos]) {
+				l.pos++
+			}
+			n, _ := strconv.Atoi(l.src[digits:l.pos])
+			if n > 255 {
+				return "", l.errorf(start, "decimal escape too large")
+			}
+			b.WriteByte(byte(n))
+		}
+	}
+}
+
+// luaExprKind identifies the kind of a luaExpr.
+type luaExprKind int
+
+const (
+	luaExprNil luaExprKind = iota
+	luaExprBool
+	luaExprNumber
+	luaExprString
+	luaExprTable
+	luaExprName  // a variable
+	luaExprIndex // left[right], or left.name with right a string
+	luaExprAnd
+	luaExprOr
+	luaExprNot
+	luaExprNeg
+	luaExprParen
+)
+
+// luaExpr is an expression node. start and end are its byte offsets in the
+// source, so that a literal can be replaced without touching anything else.
+type luaExpr struct {
+	kind       luaExprKind
+	start, end int
+
+	value  luaValue    // literals
+	name   string      // luaExprName
+	fields []*luaField // luaExprTable
+	left   *luaExpr    // luaExprIndex, luaExprParen and the operators
+	right  *luaExpr
+}
+
+// luaField is one field of a table constructor. key is nil for positional
+// fields; name is set for the "name = value" form.
+type luaField struct {
+	name  string
+	key   *luaExpr
+	value *luaExpr
+	start int // offset of the field's first token
+}
+
+// luaStmt is an assignment (targets = values) or, when ret is true, a return.
+type luaStmt struct {
+	local   bool
+	ret     bool
+	targets []*luaExpr
+	values  []*luaExpr
+}
+
+// luaChunk is a parsed config file.
+type luaChunk struct {
+	src   string
+	stmts []*luaStmt
+}
+
+type luaParser struct {
+	lex *luaLexer
+	tok luaToken
+}
+
+func parseLua(src []byte) (*luaChunk, error) {
+	p := &luaParser{lex: &luaLexer{src: string(src)}}
+	// Skip a shebang line, as lua does
+	if strings.HasPrefix(p.lex.src, "#") {
+		if nl := strings.IndexByte(p.lex.src, '\n'); nl >= 0 {
+			p.lex.pos = nl
+		} else {
+			p.lex.pos = len(p.lex.src)
+		}
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	chunk := &luaChunk{src: p.lex.src}
+	for p.tok.kind != luaEOF {
+		if p.isPunct(";") {
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		stmt, err := p.statement()
+		if err != nil {
+			return nil, err
+		}
+		chunk.stmts = append(chunk.stmts, stmt)
+		if stmt.ret {
+			if p.isPunct(";") {
+				if err := p.advance(); err != nil {
+					return nil, err
+				}
+			}
+			if p.tok.kind != luaEOF {
+				return nil, p.unexpected()
+			}
+		}
+	}
+	return chunk, nil
+}
+
+func (p *luaParser) advance() error {
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *luaParser) isPunct(text string) bool {
+	return p.tok.kind == luaPunct && p.tok.text == text
+}
+
+func (p *luaParser) isKeyword(text string) bool {
+	return p.tok.kind == luaKeyword && p.tok.text == text
+}
+
+func (p *luaParser) unexpected() error {
+	switch p.tok.kind {
+	case luaEOF:
+		return p.lex.errorf(p.tok.start, "unexpected end of file")
+	case luaKeyword:
+		if p.tok.text == "function" || p.tok.text == "if" || p.tok.text == "for" || p.tok.text == "while" {
+			return p.lex.errorf(p.tok.start, "unsupported Lua construct '%s' (only assignments of constant values are allowed)", p.tok.text)
+		}
+	}
+	return p.lex.errorf(p.tok.start, "unexpected '%s'", p.lex.src[p.tok.start:p.tok.end])
+}
+
+func (p *luaParser) expectPunct(text string) error {
+	if !p.isPunct(text) {
+		return p.unexpected()
+	}
+	return p.advance()
+}
+
+func (p *luaParser) statement() (*luaStmt, error) {
+	stmt := &luaStmt{}
+	switch {
+	case p.isKeyword("return"):
+		stmt.ret = true
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		if p.tok.kind == luaEOF || p.isPunct(";") {
+			return stmt, nil
+		}
+		values, err := p.exprList()
+		if err != nil {
+			return nil, err
+		}
+		stmt.values = values
+		return stmt, nil
+
+	case p.isKeyword("local"):
+		stmt.local = true
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		for {
+			if p.tok.kind != luaName {
+				return nil, p.unexpected()
+			}
+			stmt.targets = append(stmt.targets, &luaExpr{kind: luaExprName, name: p.tok.text, start: p.tok.start, end: p.tok.end})
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			if !p.isPunct(",") {
+				break
+			}
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+		}
+		if !p.isPunct("=") {
+			return stmt, nil
+		}
+
+	default:
+		for {
+			target, err := p.suffixedExpr()
+			if err != nil {
+				return nil, err
+			}
+			if target.kind != luaExprName && target.kind != luaExprIndex {
+				return nil, p.lex.errorf(target.start, "cannot assign to this expression")
+			}
+			stmt.targets = append(stmt.targets, target)
+			if !p.isPunct(",") {
+				break
+			}
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+		}
+		if !p.isPunct("=") {
+			return nil, p.unexpected()
+		}
+	}
+
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	values, err := p.exprList()
+	if err != nil {
+		return nil, err
+	}
+	stmt.values = values
+	return stmt, nil
+}
+
+func (p *luaParser) exprList() ([]*luaExpr, error) {
+	var list []*luaExpr
+	for {
+		e, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, e)
+		if !p.isPunct(",") {
+			return list, nil
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// expr parses "or" expressions, the lowest precedence supported.
+func (p *luaParser) expr() (*luaExpr, error) {
+	left, err := p.andExpr()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("or") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := p.andExpr()
+		if err != nil {
+			return nil, err
+		}
+		left = &luaExpr{kind: luaExprOr, left: left, right: right, start: left.start, end: right.end}
+	}
+	return left, nil
+}
+
+func (p *luaParser) andExpr() (*luaExpr, error) {
+	left, err := p.unaryExpr()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("and") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := p.unaryExpr()
+		if err != nil {
+			return nil, err
+		}
+		left = &luaExpr{kind: luaExprAnd, left: left, right: right, start: left.start, end: right.end}
+	}
+	return left, nil
+}
+
+func (p *luaParser) unaryExpr() (*luaExpr, error) {
+	var kind luaExprKind
+	switch {
+	case p.isKeyword("not"):
+		kind = luaExprNot
+	case p.isPunct("-"):
+		kind = luaExprNeg
+	default:
+		e, err := p.simpleExpr()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind == luaPunct && strings.Contains(" .. + - * / % ^ == ~= < > <= >= & | ~ ", " "+p.tok.text+" ") {
+			return nil, p.lex.errorf(p.tok.start, "unsupported operator '%s'", p.tok.text)
+		}
+		return e, nil
+	}
+	start := p.tok.start
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	operand, err := p.unaryExpr()
+	if err != nil {
+		return nil, err
+	}
+	return &luaExpr{kind: kind, left: operand, start: start, end: operand.end}, nil
+}
+
+func (p *luaParser) simpleExpr() (*luaExpr, error) {
+	tok := p.tok
+	e := &luaExpr{start: tok.start, end: tok.end}
+	switch {
+	case tok.kind == luaKeyword && tok.text == "nil":
+		e.kind = luaExprNil
+	case tok.kind == luaKeyword && (tok.text == "true" || tok.text == "false"):
+		e.kind, e.value = luaExprBool, tok.text == "true"
+	case tok.kind == luaNumber:
+		e.kind, e.value = luaExprNumber, tok.num
+	case tok.kind == luaString:
+		e.kind, e.value = luaExprString, tok.str
+	case tok.kind == luaPunct && tok.text == "{":
+		return p.tableConstructor()
+	default:
+		return p.suffixedExpr()
+	}
+	return e, p.advance()
+}
+
+// suffixedExpr parses a variable or parenthesised expression followed by
+// any number of ".name" and "[expr]" suffixes.
+func (p *luaParser) suffixedExpr() (*luaExpr, error) {
+	var e *luaExpr
+	switch {
+	case p.tok.kind == luaName:
+		e = &luaExpr{kind: luaExprName, name: p.tok.text, start: p.tok.start, end: p.tok.end}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	case p.isPunct("("):
+		start := p.tok.start
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		inner, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.isPunct(")") {
+			return nil, p.unexpected()
+		}
+		e = &luaExpr{kind: luaExprParen, left: inner, start: start, end: p.tok.end}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, p.unexpected()
+	}
+
+	for {
+		switch {
+		case p.isPunct("."):
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			if p.tok.kind != luaName {
+				return nil, p.unexpected()
+			}
+			key := &luaExpr{kind: luaExprString, value: p.tok.text, start: p.tok.start, end: p.tok.end}
+			e = &luaExpr{kind: luaExprIndex, left: e, right: key, start: e.start, end: p.tok.end}
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+		case p.isPunct("["):
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			key, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			if !p.isPunct("]") {
+				return nil, p.unexpected()
+			}
+			e = &luaExpr{kind: luaExprIndex, left: e, right: key, start: e.start, end: p.tok.end}
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+		case p.isPunct("(") || p.isPunct(":") || p.tok.kind == luaString || p.isPunct("{"):
+			return nil, p.lex.errorf(p.tok.start, "function calls are not allowed in the logger config")
+		default:
+			return e, nil
+		}
+	}
+}
+
+func (p *luaParser) tableConstructor() (*luaExpr, error) {
+	e := &luaExpr{kind: luaExprTable, start: p.tok.start}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	for !p.isPunct("}") {
+		field := &luaField{start: p.tok.start}
+		switch {
+		case p.isPunct("["):
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			key, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectPunct("]"); err != nil {
+				return nil, err
+			}
+			if err := p.expectPunct("="); err != nil {
+				return nil, err
+			}
+			field.key = key
+			if s, ok := key.value.(string); ok && key.kind == luaExprString {
+				field.name = s
+			}
+		case p.tok.kind == luaName:
+			// "name = value", unless the name starts an expression
+			save, saveTok := p.lex.pos, p.tok
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			if p.isPunct("=") {
+				field.name = saveTok.text
+				field.key = &luaExpr{kind: luaExprString, value: saveTok.text, start: saveTok.start, end: saveTok.end}
+				if err := p.advance(); err != nil {
+					return nil, err
+				}
+			} else {
+				p.lex.pos, p.tok = save, saveTok
+			}
+		}
+
+		value, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		field.value = value
+		e.fields = append(e.fields, field)
+
+		if !p.isPunct(",") && !p.isPunct(";") {
+			break
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+	if !p.isPunct("}") {
+		return nil, p.unexpected()
+	}
+	e.end = p.tok.end
+	return e, p.advance()
+}
@@ -0,0 +1,257 @@
+package manager
+
+import (
+	"strings"
+	"testing"
+
+	"rime-wanxiang-logger-go/internal/assets"
+)
+
+// evalLuaSource parses and evaluates src, failing the test on any error.
+func evalLuaSource(t *testing.T, src string) (luaValue, *luaEnv) {
+	t.Helper()
+	chunk, err := parseLua([]byte(src))
+	if err != nil {
+		t.Fatalf("parseLua: %v", err)
+	}
+	returned, env, err := evalLua(chunk)
+	if err != nil {
+		t.Fatalf("evalLua: %v", err)
+	}
+	return returned, env
+}
+
+// luaLookup follows a dotted path from the variables of env.
+func luaLookup(env *luaEnv, path string) luaValue {
+	keys := strings.Split(path, ".")
+	v := env.vars[keys[0]]
+	for _, k := range keys[1:] {
+		t, ok := v.(*luaTable)
+		if !ok {
+			return nil
+		}
+		v = t.get(k)
+	}
+	return v
+}
+
+func TestEvalLua(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		path string
+		want luaValue
+	}{
+		{"double-quoted string", `local s = "a \"b\"\tc"`, "s", "a \"b\"\tc"},
+		{"single-quoted string", `local s = 'it\'s "fine"'`, "s", `it's "fine"`},
+		{"Windows path escapes", `local s = "C:\\Users\\me\\rime.jsonl"`, "s", `C:\Users\me\rime.jsonl`},
+		{"decimal and hex escapes", `local s = "\65\x42\u{4E2D}"`, "s", "AB中"},
+		{"long string", "local s = [[C:\\Users\\me\\log.jsonl]]", "s", `C:\Users\me\log.jsonl`},
+		{"long string skips first newline", "local s = [[\nline 1\nline 2]]", "s", "line 1\nline 2"},
+		{"long string with level", "local s = [==[a ]] b]==]", "s", "a ]] b"},
+		{"trailing comment", "local n = 42 -- the answer\n", "n", 42.0},
+		{"comment with quotes", "local s = \"x\" -- it's \"quoted\"\n", "s", "x"},
+		{"block comment", "--[[ local s = \"no\" ]] local s = \"yes\"", "s", "yes"},
+		{"block comment with level", "--[==[ ]] ]==] local s = 'yes'", "s", "yes"},
+		{"comment at end without newline", "local b = true --", "b", true},
+		{"negative and hex numbers", "local n = -0x10", "n", -16.0},
+		{"float exponent", "local n = 1.5e3", "n", 1500.0},
+		{"nil value", "local v = nil", "v", nil},
+		{"not", "local b = not nil", "b", true},
+		{"and or", "local v = false and 1 or 'fallback'", "v", "fallback"},
+		{
+			name: "nested braces",
+			src: `local t = {
+				a = { b = { c = { d = "deep" } } }, -- comment }
+				["key with spaces"] = { 1, 2, { 3 } };
+			}`,
+			path: "t.a.b.c.d",
+			want: "deep",
+		},
+		{"bracket key", `local t = { ["key with spaces"] = true }`, "t.key with spaces", true},
+		{"braces inside strings", `local t = { s = "{ not a table }", l = [[}]] }`, "t.s", "{ not a table }"},
+		{"field assignment", "local t = { a = {} }\nt.a.b = 'set'\nt[\"c\"] = 1", "t.a.b", "set"},
+		{"later assignment wins", "local t = { x = 1 }\nt.x = 2", "t.x", 2.0},
+		{"nil key reads nil", "local t = {}\nlocal v = t[nil] or 'default'", "v", "default"},
+		{"global assignment", "g = 'global'", "g", "global"},
+		{"multiple assignment", "local a, b = 1, 2", "b", 2.0},
+		{"semicolons", "local a = 1; local b = 2;", "b", 2.0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, env := evalLuaSource(t, tt.src)
+			if got := luaLookup(env, tt.path); got != tt.want {
+				t.Errorf("%s = %#v, want %#v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvalLuaArrayFields(t *testing.T) {
+	_, env := evalLuaSource(t, `local t = { "a", x = true, "b", [3] = "c" }`)
+	tbl, ok := env.vars["t"].(*luaTable)
+	if !ok {
+		t.Fatalf("t is %#v, not a table", env.vars["t"])
+	}
+	for i, want := range []string{"a", "b", "c"} {
+		if got := tbl.get(float64(i + 1)); got != want {
+			t.Errorf("t[%d] = %#v, want %q", i+1, got, want)
+		}
+	}
+}
+
+func TestParseLuaRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{"function call", `local s = os.getenv("HOME")`},
+		{"method call", `local s = io:read()`},
+		{"function definition", `local f = function() end`},
+		{"string call", `print "hi"`},
+		{"if statement", `if true then x = 1 end`},
+		{"concatenation", `local s = "a" .. "b"`},
+		{"unfinished string", "local s = \"abc\nlocal t = 1"},
+		{"unfinished long string", "local s = [[abc"},
+		{"unfinished block comment", "--[[ abc"},
+		{"unbalanced braces", "local t = { a = { b = 1 }"},
+		{"invalid escape", `local s = "\q"`},
+		{"missing value", "local t = { a = }"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunk, err := parseLua([]byte(tt.src))
+			if err == nil {
+				_, _, err = evalLua(chunk)
+			}
+			if err == nil {
+				t.Errorf("expected an error for %q", tt.src)
+			}
+		})
+	}
+}
+
+func TestEvalLuaErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{"index nil", "local t = nil\nlocal v = t.x"},
+		{"assign into nil", "local t = {}\nt.a.b = 1"},
+		{"nil key", "local t = { [nil] = 1 }"},
+		{"assign to nil key", "local t = {}\nt[nil] = 1"},
+		{"negate string", `local n = -"x"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunk, err := parseLua([]byte(tt.src))
+			if err != nil {
+				t.Fatalf("parseLua: %v", err)
+			}
+			if _, _, err := evalLua(chunk); err == nil {
+				t.Errorf("expected an error for %q", tt.src)
+			}
+		})
+	}
+}
+
+// presetConfig is a config in the layout of the shipped template.
+const presetConfig = `
+-- 预设选择
+local preset_choice = %s
+
+local presets = {
+    normal = {
+        enabled = true,
+        log_events = { text_committed = true, input_state_changed = false },
+    },
+    developer = {
+        log_only_non_first_choice = true,
+        log_file_path = [[D:\rime\dev.jsonl]], -- long string path
+    },
+    custom = {
+        enabled = false,
+        log_events = {
+            error = false, -- no errors
+        },
+    },
+}
+
+return presets[preset_choice] or presets.custom
+`
+
+func TestParseLoggerConfigPresets(t *testing.T) {
+	tests := []struct {
+		name         string
+		choice       string
+		wantPreset   string
+		wantEnabled  bool
+		wantPath     string
+		wantNonFirst bool
+		wantError    bool
+	}{
+		{name: "normal", choice: `"normal"`, wantPreset: "normal", wantEnabled: true, wantError: true},
+		{name: "single-quoted choice", choice: `'developer'`, wantPreset: "developer", wantEnabled: true, wantPath: `D:\rime\dev.jsonl`, wantNonFirst: true, wantError: true},
+		{name: "custom", choice: `"custom"`, wantPreset: "custom", wantEnabled: false},
+		{name: "unknown falls back to custom", choice: `"verbose"`, wantPreset: "custom", wantEnabled: false},
+		{name: "nil falls back to custom", choice: `nil`, wantPreset: "custom", wantEnabled: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := strings.Replace(presetConfig, "%s", tt.choice, 1)
+			config, err := ParseLoggerConfig([]byte(src))
+			if err != nil {
+				t.Fatalf("ParseLoggerConfig: %v", err)
+			}
+			if config.Preset != tt.wantPreset {
+				t.Errorf("Preset = %q, want %q", config.Preset, tt.wantPreset)
+			}
+			if config.Enabled != tt.wantEnabled {
+				t.Errorf("Enabled = %v, want %v", config.Enabled, tt.wantEnabled)
+			}
+			if config.LogFilePath != tt.wantPath {
+				t.Errorf("LogFilePath = %q, want %q", config.LogFilePath, tt.wantPath)
+			}
+			if config.LogOnlyNonFirstChoice != tt.wantNonFirst {
+				t.Errorf("LogOnlyNonFirstChoice = %v, want %v", config.LogOnlyNonFirstChoice, tt.wantNonFirst)
+			}
+			// Defaults are merged in below the preset's own settings
+			if !config.LogEvents["session_start"] {
+				t.Error("default log_events.session_start was not merged in")
+			}
+			if config.LogEvents["error"] != tt.wantError {
+				t.Errorf("log_events.error = %v, want %v", config.LogEvents["error"], tt.wantError)
+			}
+		})
+	}
+}
+
+func TestParseLoggerConfigTemplate(t *testing.T) {
+	config, err := ParseLoggerConfig(assets.ConfigScript)
+	if err != nil {
+		t.Fatalf("ParseLoggerConfig: %v", err)
+	}
+	if config.PresetChoice != "normal" || config.Preset != "normal" {
+		t.Errorf("preset = %q/%q, want normal/normal", config.PresetChoice, config.Preset)
+	}
+	if !config.Enabled || !config.LogEvents["text_committed"] || config.LogEvents["input_state_changed"] {
+		t.Errorf("unexpected normal preset: %+v", config)
+	}
+	if !config.LogFields["text_committed"]["selected_candidate_rank"] {
+		t.Error("log_fields.text_committed.selected_candidate_rank is not enabled")
+	}
+}
+
+func TestParseLoggerConfigWithoutReturn(t *testing.T) {
+	config, err := ParseLoggerConfig([]byte("local presets = { custom = { enabled = false } }\n"))
+	if err != nil {
+		t.Fatalf("ParseLoggerConfig: %v", err)
+	}
+	if !config.Enabled || config.Preset != "" {
+		t.Errorf("a config that returns nothing should leave the defaults, got %+v", config)
+	}
+}
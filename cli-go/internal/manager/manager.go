@@ -2,7 +2,6 @@
 package manager
 
 import (
-	"bufio"
 	"errors"
 	"fmt"
 	"os"
@@ -10,17 +9,34 @@ import (
 	"regexp"
 	"runtime"
 	"strings"
+
+	"gopkg.in/yaml.v3"
 )
 
 // Constants for file names and configurations
 const (
 	LoggerLuaFile       = "input_habit_logger.lua"
 	ConfigLuaFile       = "input_habit_logger_config.lua"
-	SchemaYamlFile      = "wanxiang.schema.yaml"
+	DefaultSchemaID     = "wanxiang"
+	SchemaFileSuffix    = ".schema.yaml"
 	DefaultLogJsonlFile = "input_habit_log_structured.jsonl"
-	SchemaLineToAdd     = "      - lua_processor@*input_habit_logger #输入习惯记录器 - 记录用户输入习惯"
+	LoggerProcessor     = "lua_processor@*input_habit_logger"
+	LoggerComment       = "输入习惯记录器 - 记录用户输入习惯"
 )
 
+// Presets lists the preset names defined in input_habit_logger_config.lua.
+var Presets = []string{"normal", "developer", "advanced", "custom"}
+
+// IsValidPreset reports whether name is one of Presets.
+func IsValidPreset(name string) bool {
+	for _, p := range Presets {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
 // RimeManager handles all interactions with the Rime user directory.
 type RimeManager struct {
 	UserDirectory string
@@ -34,7 +50,12 @@ func NewRimeManager() (*RimeManager, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to detect Rime user directory: %w", err)
 	}
+	return NewRimeManagerAt(userDir)
+}
 
+// NewRimeManagerAt creates a new RimeManager for the given user directory,
+// which must exist.
+func NewRimeManagerAt(userDir string) (*RimeManager, error) {
 	info, err := os.Stat(userDir)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -52,50 +73,39 @@ func NewRimeManager() (*RimeManager, error) {
 	}, nil
 }
 
-// GetRimeUserDirectory detects the default Rime user directory based on the OS.
+// GetRimeUserDirectory returns the directory named by $RIME_USER_DIR, else
+// the default Rime user directory based on the OS. On Linux the first
+// existing frontend directory is used (see DetectFrontends).
 // See: https://github.com/rime/home/blob/master/README.md#user-data-directory
 func GetRimeUserDirectory() (string, error) {
+	if dir := os.Getenv(RimeDirEnv); dir != "" {
+		return dir, nil
+	}
+
 	switch runtime.GOOS {
 	case "windows":
 		// Windows: %APPDATA%\Rime
-		appData := os.Getenv("APPDATA")
-		if appData == "" {
+		if os.Getenv("APPDATA") == "" {
 			return "", errors.New("%APPDATA% environment variable not set")
 		}
-		return filepath.Join(appData, "Rime"), nil
-	case "darwin":
-		// macOS: ~/Library/Rime
-		homeDir, err := os.UserHomeDir()
-		if err != nil {
+	case "darwin", "linux":
+		// macOS: ~/Library/Rime; Linux: ~/.config/rime and the frontend directories
+		if _, err := os.UserHomeDir(); err != nil {
 			return "", fmt.Errorf("could not get user home directory: %w", err)
 		}
-		return filepath.Join(homeDir, "Library", "Rime"), nil
-	case "linux":
-		// Linux: check multiple possible locations
-		homeDir, err := os.UserHomeDir()
-		if err != nil {
-			return "", fmt.Errorf("could not get user home directory: %w", err)
-		}
-
-		// Check common locations in order of preference
-		possiblePaths := []string{
-			filepath.Join(homeDir, ".config", "rime"),
-			filepath.Join(homeDir, ".config", "fcitx", "rime"),
-			filepath.Join(homeDir, ".config", "fcitx5", "rime"),
-			filepath.Join(homeDir, ".config", "ibus", "rime"),
-		}
-
-		for _, path := range possiblePaths {
-			if _, err := os.Stat(path); err == nil {
-				return path, nil
-			}
-		}
-
-		// If none exist, default to the most common location
-		return possiblePaths[0], nil
 	default:
 		return "", fmt.Errorf("unsupported operating system: %s", runtime.GOOS)
 	}
+
+	candidates := frontendCandidates()
+	for _, f := range candidates {
+		if _, err := os.Stat(f.Directory); err == nil {
+			return f.Directory, nil
+		}
+	}
+
+	// If none exist, default to the most common location
+	return candidates[0].Directory, nil
 }
 
 // GetLuaDirectory returns the path to the 'lua' subdirectory within the Rime user directory.
@@ -103,56 +113,20 @@ func (m *RimeManager) GetLuaDirectory() string {
 	return m.LuaDirectory
 }
 
-// GetLogFilePath gets the log file path by parsing the active preset in the Lua config file.
+// GetLogFilePath returns the log file the logger writes to: log_file_path of
+// the effective config (see LoadLoggerConfig), or the default location.
 // This mimics the Python version's _get_log_file_path method.
 func (m *RimeManager) GetLogFilePath() (string, error) {
 	defaultPath := filepath.Join(m.UserDirectory, DefaultLogJsonlFile)
-	configPath := filepath.Join(m.LuaDirectory, ConfigLuaFile)
-
-	// If config file doesn't exist, return default path
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		return defaultPath, nil
-	}
 
-	// Read and parse the config file
-	content, err := os.ReadFile(configPath)
+	config, err := m.LoadLoggerConfig()
 	if err != nil {
-		fmt.Printf("Warning: Could not read config file, using default log path. Error: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Warning: Could not load config file, using default log path. Error: %v\n", err)
 		return defaultPath, nil
 	}
 
-	contentStr := string(content)
-
-	// 1. Find the active preset choice
-	presetChoiceRegex := regexp.MustCompile(`local\s+preset_choice\s*=\s*"([^"]+)"`)
-	presetMatch := presetChoiceRegex.FindStringSubmatch(contentStr)
-	if len(presetMatch) < 2 {
-		return defaultPath, nil
-	}
-
-	activePreset := presetMatch[1]
-
-	// 2. Find the configuration block for the active preset
-	// This regex looks for `preset_name = {` and captures everything until the matching `}`
-	presetBlockRegex := regexp.MustCompile(fmt.Sprintf(`^\s*%s\s*=\s*\{([\s\S]*?)\n\s*\}`, regexp.QuoteMeta(activePreset)))
-	presetBlockMatch := presetBlockRegex.FindStringSubmatch(contentStr)
-	if len(presetBlockMatch) < 2 {
-		return defaultPath, nil
-	}
-
-	presetContent := presetBlockMatch[1]
-
-	// 3. Search for a non-commented log_file_path within that block
-	logPathRegex := regexp.MustCompile(`^\s*log_file_path\s*=\s*"([^"]+)"`)
-	logPathMatch := logPathRegex.FindStringSubmatch(presetContent)
-
-	if len(logPathMatch) >= 2 {
-		// Path must be un-escaped (e.g., "C:\\Users" -> "C:\Users")
-		customPathStr := strings.ReplaceAll(logPathMatch[1], `\\`, `\`)
-		if customPathStr != "" {
-			fmt.Printf("Found active custom log path: %s\n", customPathStr)
-			return customPathStr, nil
-		}
+	if config.LogFilePath != "" {
+		return config.LogFilePath, nil
 	}
 
 	return defaultPath, nil
@@ -169,165 +143,126 @@ func (m *RimeManager) CheckScriptsInstalled() (bool, bool) {
 	return loggerErr == nil, configErr == nil
 }
 
-// CheckSchemaConfigured checks if the schema file is configured with the logger.
-func (m *RimeManager) CheckSchemaConfigured() (bool, error) {
-	schemaPath := filepath.Join(m.UserDirectory, SchemaYamlFile)
+// luaRequire matches a plain require of a module, e.g. require "lib". Guarded
+// requires through pcall are optional and deliberately not matched.
+var luaRequire = regexp.MustCompile(`(?m)\brequire\s*\(?\s*["']([\w.]+)["']`)
 
-	content, err := os.ReadFile(schemaPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return false, fmt.Errorf("schema file not found: %s", schemaPath)
-		}
-		return false, fmt.Errorf("could not read schema file: %w", err)
+// LuaRequires returns the modules a Lua script requires unconditionally.
+func LuaRequires(script []byte) []string {
+	var modules []string
+	for _, match := range luaRequire.FindAllSubmatch(script, -1) {
+		modules = append(modules, string(match[1]))
 	}
-
-	return strings.Contains(string(content), strings.TrimSpace(SchemaLineToAdd)), nil
+	return modules
 }
 
-// GetSchemaPath returns the path to the wanxiang.schema.yaml file.
-func (m *RimeManager) GetSchemaPath() string {
-	return filepath.Join(m.UserDirectory, SchemaYamlFile)
+// ResolveLuaModule returns the file librime-lua would load for a module from
+// the lua directory: lua/<name>.lua or lua/<name>/init.lua, with dots in the
+// name as path separators. It reports false if neither exists.
+func (m *RimeManager) ResolveLuaModule(name string) (string, bool) {
+	base := filepath.Join(m.LuaDirectory, filepath.FromSlash(strings.ReplaceAll(name, ".", "/")))
+	for _, path := range []string{base + ".lua", filepath.Join(base, "init.lua")} {
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, true
+		}
+	}
+	return "", false
 }
 
-// BackupSchemaFile creates a backup of the schema file before modification.
-func (m *RimeManager) BackupSchemaFile() error {
-	schemaPath := m.GetSchemaPath()
-	backupPath := schemaPath + ".bak"
-
-	input, err := os.ReadFile(schemaPath)
-	if err != nil {
-		return fmt.Errorf("failed to read schema file: %w", err)
+// CheckWritable checks if the logger could append to the file at path,
+// creating it if it does not exist yet.
+func CheckWritable(path string) error {
+	if f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0); err == nil {
+		return f.Close()
+	} else if !os.IsNotExist(err) {
+		return err
 	}
 
-	err = os.WriteFile(backupPath, input, 0644)
+	f, err := os.CreateTemp(filepath.Dir(path), ".write-test-*")
 	if err != nil {
-		return fmt.Errorf("failed to create backup file: %w", err)
+		return err
 	}
-
-	fmt.Printf("Schema file backed up to: %s\n", backupPath)
-	return nil
+	f.Close()
+	return os.Remove(f.Name())
 }
 
-// ModifySchemaForInstall adds the lua_processor line to the schema file.
-func (m *RimeManager) ModifySchemaForInstall() error {
-	schemaPath := m.GetSchemaPath()
+// CheckSchemaConfigured checks if engine/processors in the schema file lists the logger.
+func (m *RimeManager) CheckSchemaConfigured(schemaID string) (bool, error) {
+	schemaPath := m.GetSchemaPath(schemaID)
 
-	// Check if file exists
-	if _, err := os.Stat(schemaPath); os.IsNotExist(err) {
-		return fmt.Errorf("schema file not found: %s. Please ensure the 'wanxiang' input method is installed and deployed at least once", schemaPath)
+	content, err := os.ReadFile(schemaPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, fmt.Errorf("schema file not found: %s", schemaPath)
+		}
+		return false, fmt.Errorf("could not read schema file: %w", err)
 	}
 
-	// Read the file
-	file, err := os.Open(schemaPath)
+	src, err := parseYAMLSource(content)
 	if err != nil {
-		return fmt.Errorf("failed to open schema file: %w", err)
+		return false, fmt.Errorf("could not parse %s: %w", SchemaFileName(schemaID), err)
 	}
-	defer file.Close()
+	processors := schemaProcessors(src.root())
+	return sequenceIndex(processors, func(v string) bool { return v == LoggerProcessor }) >= 0, nil
+}
 
-	var lines []string
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
-	}
+// SchemaFileName returns the file name of a schema, e.g. "wanxiang.schema.yaml".
+func SchemaFileName(schemaID string) string {
+	return schemaID + SchemaFileSuffix
+}
 
-	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("failed to read schema file: %w", err)
-	}
+// GetSchemaPath returns the path to the <schemaID>.schema.yaml file.
+func (m *RimeManager) GetSchemaPath(schemaID string) string {
+	return filepath.Join(m.UserDirectory, SchemaFileName(schemaID))
+}
 
-	// Check if already configured
-	lineToAdd := strings.TrimSpace(SchemaLineToAdd)
-	for _, line := range lines {
-		if strings.Contains(line, lineToAdd) {
-			fmt.Println("Schema is already configured, no changes needed.")
-			return nil
-		}
-	}
+// PlanSchemaInstall plans inserting the logger into engine/processors of the
+// schema file at pos. Only that one line changes; comments and formatting are
+// kept. It reports false if the logger is already listed.
+func (m *RimeManager) PlanSchemaInstall(p *Plan, schemaID string, pos ProcessorPosition) (bool, error) {
+	schemaPath := m.GetSchemaPath(schemaID)
 
-	// Find the punctuator line to insert after it
-	punctuatorIndex := -1
-	for i, line := range lines {
-		if strings.Contains(line, "punctuator") && strings.TrimSpace(line)[0] == '-' {
-			punctuatorIndex = i
-			break
-		}
+	content, exists, err := p.Content(schemaPath)
+	if err != nil {
+		return false, err
 	}
-
-	if punctuatorIndex == -1 {
-		return errors.New("could not find 'punctuator' entry in schema file")
+	if !exists {
+		return false, fmt.Errorf("schema file not found: %s. Please ensure the '%s' input method is installed and deployed at least once", schemaPath, schemaID)
 	}
 
-	// Get the indentation from the punctuator line
-	punctuatorLine := lines[punctuatorIndex]
-	indentation := ""
-	for _, char := range punctuatorLine {
-		if char == ' ' || char == '\t' {
-			indentation += string(char)
-		} else {
-			break
-		}
+	src, err := parseYAMLSource(content)
+	if err != nil {
+		return false, fmt.Errorf("could not parse %s: %w", SchemaFileName(schemaID), err)
 	}
-
-	// Create the new line with proper indentation
-	lineWithIndent := indentation + lineToAdd
-
-	// Insert the line after punctuator
-	newLines := make([]string, 0, len(lines)+1)
-	newLines = append(newLines, lines[:punctuatorIndex+1]...)
-	newLines = append(newLines, lineWithIndent)
-	newLines = append(newLines, lines[punctuatorIndex+1:]...)
-
-	// Write back to file
-	content := strings.Join(newLines, "\n") + "\n"
-	if err := os.WriteFile(schemaPath, []byte(content), 0644); err != nil {
-		return fmt.Errorf("failed to write schema file: %w", err)
+	changed, err := src.insertProcessor(schemaProcessors(src.root()), LoggerProcessor, LoggerComment, pos)
+	if err != nil || !changed {
+		return false, err
 	}
-
-	fmt.Printf("Successfully configured '%s'.\n", SchemaYamlFile)
-	return nil
+	return true, p.Write(schemaPath, src.bytes())
 }
 
-// RevertSchemaForUninstall removes the lua_processor line from the schema file.
-func (m *RimeManager) RevertSchemaForUninstall() error {
-	schemaPath := m.GetSchemaPath()
+// PlanSchemaUninstall plans removing the logger from engine/processors of the
+// schema file. A missing schema file is not an error. It reports false if
+// there is nothing to remove.
+func (m *RimeManager) PlanSchemaUninstall(p *Plan, schemaID string) (bool, error) {
+	schemaPath := m.GetSchemaPath(schemaID)
 
-	// Check if file exists
-	if _, err := os.Stat(schemaPath); os.IsNotExist(err) {
-		fmt.Println("Schema file not found, skipping.")
-		return nil
+	content, exists, err := p.Content(schemaPath)
+	if err != nil || !exists {
+		return false, err
 	}
 
-	// Read the file
-	content, err := os.ReadFile(schemaPath)
+	src, err := parseYAMLSource(content)
 	if err != nil {
-		return fmt.Errorf("failed to read schema file: %w", err)
+		return false, fmt.Errorf("could not parse %s: %w", SchemaFileName(schemaID), err)
 	}
-
-	lines := strings.Split(string(content), "\n")
-	lineToRemove := strings.TrimSpace(SchemaLineToAdd)
-
-	// Filter out lines containing the logger configuration
-	var filteredLines []string
-	removed := false
-	for _, line := range lines {
-		if !strings.Contains(line, lineToRemove) {
-			filteredLines = append(filteredLines, line)
-		} else {
-			removed = true
-		}
+	removed, err := src.removeProcessor(func(s *yamlSource) *yaml.Node {
+		return schemaProcessors(s.root())
+	}, LoggerProcessor)
+	if err != nil || !removed {
+		return false, err
 	}
-
-	if removed {
-		// Write back to file
-		newContent := strings.Join(filteredLines, "\n")
-		if err := os.WriteFile(schemaPath, []byte(newContent), 0644); err != nil {
-			return fmt.Errorf("failed to write schema file: %w", err)
-		}
-		fmt.Printf("Successfully removed logger configuration from '%s'.\n", SchemaYamlFile)
-	} else {
-		fmt.Println("Logger configuration not found in schema file, no changes needed.")
-	}
-
-	return nil
+	return true, p.Write(schemaPath, src.bytes())
 }
 
 // LogFileExists checks if the log file exists at the determined path.
@@ -0,0 +1,195 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Install modes: edit the schema file itself, or patch it through <schema>.custom.yaml.
+const (
+	ModeSchema = "schema"
+	ModePatch  = "patch"
+)
+
+// Constants for the *.custom.yaml patch
+const (
+	CustomFileSuffix  = ".custom.yaml"
+	PatchProcessorKey = "engine/processors/@before 0"
+)
+
+// patchLineToAdd is the entry inserted under patch:, without indentation.
+var patchLineToAdd = fmt.Sprintf("%q: %s #%s", PatchProcessorKey, LoggerProcessor, LoggerComment)
+
+// ErrPatchNotManaged is returned when the logger appears in a custom patch
+// in a form this tool did not write and cannot safely remove.
+var ErrPatchNotManaged = errors.New("logger processor was not added by this tool")
+
+// CustomFileName returns the file name of a schema's patch file, e.g. "wanxiang.custom.yaml".
+func CustomFileName(schemaID string) string {
+	return schemaID + CustomFileSuffix
+}
+
+// GetCustomPatchPath returns the path to the <schemaID>.custom.yaml file.
+func (m *RimeManager) GetCustomPatchPath(schemaID string) string {
+	return filepath.Join(m.UserDirectory, CustomFileName(schemaID))
+}
+
+// CheckPatchConfigured checks if <schemaID>.custom.yaml patches the logger in.
+// A missing patch file is not an error.
+func (m *RimeManager) CheckPatchConfigured(schemaID string) (bool, error) {
+	content, err := os.ReadFile(m.GetCustomPatchPath(schemaID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("could not read patch file: %w", err)
+	}
+
+	src, err := parseYAMLSource(content)
+	if err != nil {
+		return false, fmt.Errorf("could not parse %s: %w", CustomFileName(schemaID), err)
+	}
+	_, patch := mappingEntry(src.root(), "patch")
+	return containsScalar(patch, LoggerProcessor), nil
+}
+
+// PlanPatchInstall plans adding the logger processor to <schemaID>.custom.yaml,
+// creating the file if needed. Existing patches, comments and formatting are
+// left untouched. pos only applies when the patch replaces engine/processors
+// as a whole. It reports false if the patch already loads the logger.
+func (m *RimeManager) PlanPatchInstall(p *Plan, schemaID string, pos ProcessorPosition) (bool, error) {
+	path := m.GetCustomPatchPath(schemaID)
+
+	content, _, err := p.Content(path)
+	if err != nil {
+		return false, err
+	}
+
+	updated, changed, err := addLoggerPatch(content, pos)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", CustomFileName(schemaID), err)
+	}
+	if !changed {
+		return false, nil
+	}
+	return true, p.Write(path, updated)
+}
+
+// PlanPatchUninstall plans removing the entry written by PlanPatchInstall.
+// The rest of the patch file is kept, even if the patch becomes empty.
+// It reports false if there is nothing to remove.
+func (m *RimeManager) PlanPatchUninstall(p *Plan, schemaID string) (bool, error) {
+	path := m.GetCustomPatchPath(schemaID)
+
+	content, exists, err := p.Content(path)
+	if err != nil || !exists {
+		return false, err
+	}
+
+	updated, changed, err := removeLoggerPatch(content)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", CustomFileName(schemaID), err)
+	}
+	if !changed {
+		return false, nil
+	}
+	return true, p.Write(path, updated)
+}
+
+// addLoggerPatch inserts patchLineToAdd as the first entry of the top-level
+// patch mapping, adding the mapping if there is none. If the patch already
+// replaces engine/processors as a whole, the logger is added to that list instead.
+func addLoggerPatch(content []byte, pos ProcessorPosition) ([]byte, bool, error) {
+	src, err := parseYAMLSource(content)
+	if err != nil {
+		return nil, false, err
+	}
+
+	root := src.root()
+	if root == nil && len(src.doc.Content) > 0 && src.doc.Content[0].Tag != "!!null" {
+		return nil, false, errors.New("top level is not a mapping")
+	}
+
+	patchKey, patch := mappingEntry(root, "patch")
+	if patchKey == nil {
+		text, nl := string(content), src.newline()
+		if len(text) == 0 {
+			text = "# encoding: utf-8" + nl
+		} else if !strings.HasSuffix(text, "\n") {
+			text += nl
+		}
+		text += nl + "patch:" + nl + "  " + patchLineToAdd + nl
+		return []byte(text), true, nil
+	}
+
+	if containsScalar(patch, LoggerProcessor) {
+		return content, false, nil
+	}
+
+	if processors := patchProcessors(patch); processors != nil {
+		changed, err := src.insertProcessor(processors, LoggerProcessor, LoggerComment, pos)
+		if err != nil {
+			return nil, false, err
+		}
+		return src.bytes(), changed, nil
+	}
+
+	indent := "  "
+	switch {
+	case patch.Kind == yaml.ScalarNode && patch.Tag == "!!null" && patch.Value == "":
+		// "patch:" with nothing under it
+	case patch.Kind == yaml.MappingNode && patch.Style&yaml.FlowStyle == 0:
+		for i := 0; i+1 < len(patch.Content); i += 2 {
+			switch key := patch.Content[i].Value; key {
+			case PatchProcessorKey:
+				return nil, false, fmt.Errorf("patch key %q is already used for something else", key)
+			case "engine":
+				return nil, false, errors.New("patch replaces engine without a processors list")
+			}
+		}
+		if len(patch.Content) > 0 {
+			indent = strings.Repeat(" ", patch.Content[0].Column-1)
+		}
+	default:
+		return nil, false, errors.New("patch must be a block mapping")
+	}
+
+	src.insertLine(patchKey.Line, indent+patchLineToAdd) // the line right after "patch:"
+	return src.bytes(), true, nil
+}
+
+// removeLoggerPatch deletes the line holding the PatchProcessorKey entry, or
+// the logger's item in a patched engine/processors list.
+func removeLoggerPatch(content []byte) ([]byte, bool, error) {
+	src, err := parseYAMLSource(content)
+	if err != nil {
+		return nil, false, err
+	}
+
+	_, patch := mappingEntry(src.root(), "patch")
+	key, value := mappingEntry(patch, PatchProcessorKey)
+	if key != nil && value.Value == LoggerProcessor && key.Line == value.Line {
+		src.deleteLine(key.Line - 1)
+		return src.bytes(), true, nil
+	}
+
+	removed, err := src.removeProcessor(func(s *yamlSource) *yaml.Node {
+		_, patch := mappingEntry(s.root(), "patch")
+		return patchProcessors(patch)
+	}, LoggerProcessor)
+	if err != nil {
+		return nil, false, err
+	}
+	if !removed {
+		_, patch := mappingEntry(src.root(), "patch")
+		if containsScalar(patch, LoggerProcessor) {
+			return nil, false, ErrPatchNotManaged
+		}
+	}
+	return src.bytes(), removed, nil
+}
@@ -0,0 +1,194 @@
+package manager
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// ChangeKind says what a Change does to its file.
+type ChangeKind string
+
+// Change kinds
+const (
+	ChangeCreate ChangeKind = "create"
+	ChangeModify ChangeKind = "modify"
+	ChangeRemove ChangeKind = "remove"
+)
+
+// Change is one planned file operation.
+type Change struct {
+	Kind ChangeKind `json:"kind" yaml:"kind"`
+	Path string     `json:"path" yaml:"path"`
+
+	existed bool   // whether the file was on disk when the change was planned
+	before  []byte // content on disk when the change was planned
+	after   []byte // content to write, unused for ChangeRemove
+}
+
+// Diff returns the change as a unified diff.
+func (c *Change) Diff() string {
+	from, to := "a/"+filepath.Base(c.Path), "b/"+filepath.Base(c.Path)
+	switch c.Kind {
+	case ChangeCreate:
+		from = "/dev/null"
+	case ChangeRemove:
+		to = "/dev/null"
+	}
+
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitDiffLines(c.before),
+		B:        splitDiffLines(c.after),
+		FromFile: from,
+		ToFile:   to,
+		Context:  3,
+	})
+	if err != nil {
+		return ""
+	}
+	return diff
+}
+
+// LineCount returns the number of lines written (or removed, for ChangeRemove).
+func (c *Change) LineCount() int {
+	if c.Kind == ChangeRemove {
+		return len(splitDiffLines(c.before))
+	}
+	return len(splitDiffLines(c.after))
+}
+
+func splitDiffLines(content []byte) []string {
+	if len(content) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(content), "\n")
+	if last := lines[len(lines)-1]; last == "" {
+		lines = lines[:len(lines)-1]
+	} else {
+		lines[len(lines)-1] = last + "\n\\ No newline at end of file\n"
+	}
+	return lines
+}
+
+// Plan is the list of file changes an install or uninstall would make.
+// Planning only reads from disk; ApplyPlan performs the changes, so a dry run
+// and a real run are driven by the same Plan.
+type Plan struct {
+	changes []*Change
+}
+
+// NewPlan returns an empty Plan.
+func NewPlan() *Plan {
+	return &Plan{}
+}
+
+// Changes returns the planned changes in order.
+func (p *Plan) Changes() []*Change {
+	return p.changes
+}
+
+// Empty reports whether the plan changes nothing.
+func (p *Plan) Empty() bool {
+	return len(p.changes) == 0
+}
+
+func (p *Plan) find(path string) *Change {
+	for _, c := range p.changes {
+		if c.Path == path {
+			return c
+		}
+	}
+	return nil
+}
+
+// Content returns what path will contain once the changes planned so far are
+// applied. exists is false if the file is absent or planned for removal.
+func (p *Plan) Content(path string) (content []byte, exists bool, err error) {
+	if c := p.find(path); c != nil {
+		if c.Kind == ChangeRemove {
+			return nil, false, nil
+		}
+		return c.after, true, nil
+	}
+
+	content, err = os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return content, true, nil
+}
+
+// Write plans writing content to path. Writing what the file already contains
+// is not a change.
+func (p *Plan) Write(path string, content []byte) error {
+	current, exists, err := p.Content(path)
+	if err != nil {
+		return err
+	}
+	if exists && bytes.Equal(current, content) {
+		return nil
+	}
+
+	c := p.find(path)
+	if c == nil {
+		c = &Change{Path: path, existed: exists, before: current}
+		p.changes = append(p.changes, c)
+	}
+	c.after = content
+	c.Kind = ChangeModify
+	if !c.existed {
+		c.Kind = ChangeCreate
+	}
+	return nil
+}
+
+// Remove plans deleting path. Removing a missing file is not a change.
+func (p *Plan) Remove(path string) error {
+	current, exists, err := p.Content(path)
+	if err != nil || !exists {
+		return err
+	}
+
+	c := p.find(path)
+	if c == nil {
+		c = &Change{Path: path, existed: true, before: current}
+		p.changes = append(p.changes, c)
+	}
+	c.after = nil
+	c.Kind = ChangeRemove
+	return nil
+}
+
+// ApplyPlan performs every change of the plan in order. When backup is not
+// nil, each file is saved to it before being touched.
+func (m *RimeManager) ApplyPlan(p *Plan, backup *BackupSet) error {
+	for _, c := range p.changes {
+		if backup != nil {
+			if err := backup.Add(c.Path); err != nil {
+				return err
+			}
+		}
+
+		switch c.Kind {
+		case ChangeRemove:
+			if err := os.Remove(c.Path); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove %s: %w", c.Path, err)
+			}
+		default:
+			if err := os.MkdirAll(filepath.Dir(c.Path), 0755); err != nil {
+				return fmt.Errorf("failed to create directory for %s: %w", c.Path, err)
+			}
+			if err := os.WriteFile(c.Path, c.after, 0644); err != nil {
+				return fmt.Errorf("failed to write %s: %w", c.Path, err)
+			}
+		}
+	}
+	return nil
+}
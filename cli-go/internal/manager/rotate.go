@@ -0,0 +1,233 @@
+package manager
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Constants for log archives
+const (
+	LogsDirName       = "logs"
+	LogArchiveSuffix  = ".jsonl.gz"
+	rotatingLogSuffix = ".rotating"
+)
+
+// logArchiveName matches "<time>[-<n>]_<log name>.jsonl.gz" as written by RotateLog.
+var logArchiveName = regexp.MustCompile(`^(\d{8}-\d{6})(?:-(\d+))?_`)
+
+// RotationPolicy decides when the live log is due for rotation.
+type RotationPolicy struct {
+	// MaxSize rotates the log once it reaches this many bytes. Zero disables it.
+	MaxSize int64
+	// MaxAge rotates the log once its oldest entry is older than this. Zero disables it.
+	MaxAge time.Duration
+}
+
+// Due reports whether a log of size bytes whose first entry was written at
+// oldest should be rotated at now, and why.
+func (p RotationPolicy) Due(size int64, oldest, now time.Time) (bool, string) {
+	if p.MaxSize > 0 && size >= p.MaxSize {
+		return true, fmt.Sprintf("size %d >= %d bytes", size, p.MaxSize)
+	}
+	if p.MaxAge > 0 && !oldest.IsZero() && now.Sub(oldest) >= p.MaxAge {
+		return true, fmt.Sprintf("oldest entry %s is older than %s", oldest.Format(time.RFC3339), p.MaxAge)
+	}
+	return false, ""
+}
+
+// LogArchive is one compressed log written by RotateLog.
+type LogArchive struct {
+	Path string `json:"path" yaml:"path"`
+	// Size is the size of the uncompressed log.
+	Size int64 `json:"size" yaml:"size"`
+	// CompressedSize is the size of the .jsonl.gz file.
+	CompressedSize int64 `json:"compressed_size" yaml:"compressed_size"`
+}
+
+// GetLogsDirectory returns the logs/ directory holding the log archives.
+func (m *RimeManager) GetLogsDirectory() string {
+	return filepath.Join(m.UserDirectory, LogsDirName)
+}
+
+// LogArchives returns the archives in logs/, oldest first.
+func (m *RimeManager) LogArchives() ([]string, error) {
+	return logArchivesIn(m.UserDirectory)
+}
+
+// LogFilesIn returns the log files found in a Rime user directory copied
+// from another machine, in the order they were written: the archives in
+// logs/ then the log at its default location. Unlike LogFiles, the
+// directory's config is not consulted, as a custom log_file_path would
+// name a path on that machine.
+func LogFilesIn(dir string) ([]string, error) {
+	files, err := logArchivesIn(dir)
+	if err != nil {
+		return nil, err
+	}
+	logFilePath := filepath.Join(dir, DefaultLogJsonlFile)
+	for _, path := range []string{logFilePath + rotatingLogSuffix, logFilePath} {
+		if _, err := os.Stat(path); err == nil {
+			files = append(files, path)
+		}
+	}
+	return files, nil
+}
+
+// logArchivesIn returns the archives in dir/logs, oldest first.
+func logArchivesIn(dir string) ([]string, error) {
+	archives, err := filepath.Glob(filepath.Join(dir, LogsDirName, "*"+LogArchiveSuffix))
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(archives, func(i, j int) bool {
+		ti, ni := archiveOrder(archives[i])
+		tj, nj := archiveOrder(archives[j])
+		if ti != tj {
+			return ti < tj
+		}
+		if ni != nj {
+			return ni < nj
+		}
+		return archives[i] < archives[j]
+	})
+	return archives, nil
+}
+
+// archiveOrder returns the rotation time and sequence number in an archive's name.
+func archiveOrder(path string) (string, int) {
+	match := logArchiveName.FindStringSubmatch(filepath.Base(path))
+	if match == nil {
+		return "", 0
+	}
+	n, _ := strconv.Atoi(match[2])
+	return match[1], n
+}
+
+// LogFiles returns every file holding log entries, in the order they were
+// written: the archives in logs/, a log left over by an interrupted rotation,
+// then the live log. Files that do not exist are left out.
+func (m *RimeManager) LogFiles() ([]string, error) {
+	files, err := m.LogArchives()
+	if err != nil {
+		return nil, err
+	}
+	logFilePath, err := m.GetLogFilePath()
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range []string{logFilePath + rotatingLogSuffix, logFilePath} {
+		if _, err := os.Stat(path); err == nil {
+			files = append(files, path)
+		}
+	}
+	return files, nil
+}
+
+// RotateLog moves the live log into a gzip-compressed archive in logs/. The
+// log is renamed first, so the logger, which opens the file for every entry,
+// starts a new one right away. It returns nil if there is no log to rotate.
+func (m *RimeManager) RotateLog(now time.Time) ([]LogArchive, error) {
+	logFilePath, err := m.GetLogFilePath()
+	if err != nil {
+		return nil, err
+	}
+	rotating := logFilePath + rotatingLogSuffix
+	logsDir := m.GetLogsDirectory()
+	if err := os.MkdirAll(logsDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create logs directory: %w", err)
+	}
+
+	var archives []LogArchive
+	// Finish an earlier rotation that was interrupted after the rename
+	if _, err := os.Stat(rotating); err == nil {
+		archive, err := archiveLog(rotating, logsDir, logFilePath, now)
+		if err != nil {
+			return archives, err
+		}
+		archives = append(archives, archive)
+	}
+
+	if info, err := os.Stat(logFilePath); err != nil || info.Size() == 0 {
+		return archives, nil
+	}
+	if err := os.Rename(logFilePath, rotating); err != nil {
+		return archives, fmt.Errorf("failed to move log file: %w", err)
+	}
+	archive, err := archiveLog(rotating, logsDir, logFilePath, now)
+	if err != nil {
+		return archives, err
+	}
+	return append(archives, archive), nil
+}
+
+// archiveLog compresses src into a new archive in dir named after the live
+// log, then removes src.
+func archiveLog(src, dir, logFilePath string, now time.Time) (LogArchive, error) {
+	archive := LogArchive{}
+	in, err := os.Open(src)
+	if err != nil {
+		return archive, err
+	}
+	defer in.Close()
+
+	name := strings.TrimSuffix(filepath.Base(logFilePath), filepath.Ext(logFilePath))
+	out, path, err := createArchive(dir, now.Format(backupTimeFormat), name)
+	if err != nil {
+		return archive, err
+	}
+	archive.Path = path
+
+	gz := gzip.NewWriter(out)
+	gz.Name = filepath.Base(logFilePath)
+	gz.ModTime = now
+	archive.Size, err = io.Copy(gz, in)
+	if err == nil {
+		err = gz.Close()
+	}
+	if err == nil {
+		err = out.Sync()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return archive, fmt.Errorf("failed to write archive %s: %w", path, err)
+	}
+
+	if info, err := os.Stat(path); err == nil {
+		archive.CompressedSize = info.Size()
+	}
+	in.Close()
+	if err := os.Remove(src); err != nil {
+		return archive, fmt.Errorf("archived %s but could not remove it: %w", src, err)
+	}
+	return archive, nil
+}
+
+// createArchive creates a new, uniquely named archive file in dir.
+func createArchive(dir, stamp, name string) (*os.File, string, error) {
+	for n := 1; ; n++ {
+		prefix := stamp
+		if n > 1 {
+			prefix = fmt.Sprintf("%s-%d", stamp, n)
+		}
+		path := filepath.Join(dir, prefix+"_"+name+LogArchiveSuffix)
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to create archive: %w", err)
+		}
+		return f, path, nil
+	}
+}
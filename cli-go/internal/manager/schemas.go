@@ -0,0 +1,282 @@
+package manager
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaInfo describes a schema found in the Rime user directory.
+type SchemaInfo struct {
+	ID   string `json:"id" yaml:"id"`
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	Path string `json:"path" yaml:"path"`
+	// Active is true when the schema appears in the effective schema_list.
+	Active bool `json:"active" yaml:"active"`
+}
+
+// schemaHeader is the subset of a *.schema.yaml file needed for discovery.
+type schemaHeader struct {
+	Schema struct {
+		SchemaID string `yaml:"schema_id"`
+		Name     string `yaml:"name"`
+	} `yaml:"schema"`
+}
+
+type schemaListEntry struct {
+	Schema string `yaml:"schema"`
+}
+
+// DiscoverSchemas scans the user directory for *.schema.yaml files and marks
+// those enabled in schema_list. Schemas that only exist in build/ (deployed
+// from the shared data directory) are included too, since they can still be
+// patched through *.custom.yaml. Active schemas are listed first, in
+// schema_list order, followed by the rest alphabetically.
+func (m *RimeManager) DiscoverSchemas() ([]SchemaInfo, error) {
+	matches, err := filepath.Glob(filepath.Join(m.UserDirectory, "*"+SchemaFileSuffix))
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan for schemas: %w", err)
+	}
+	built, err := filepath.Glob(filepath.Join(m.UserDirectory, "build", "*"+SchemaFileSuffix))
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan for schemas: %w", err)
+	}
+	seen := make(map[string]bool, len(matches))
+	for _, path := range matches {
+		seen[filepath.Base(path)] = true
+	}
+	for _, path := range built {
+		if !seen[filepath.Base(path)] {
+			matches = append(matches, path)
+		}
+	}
+
+	active := m.ActiveSchemaIDs()
+	order := make(map[string]int, len(active))
+	for i, id := range active {
+		order[id] = i
+	}
+
+	var schemas []SchemaInfo
+	for _, path := range matches {
+		id := strings.TrimSuffix(filepath.Base(path), SchemaFileSuffix)
+		info := SchemaInfo{ID: id, Path: path}
+
+		if content, err := os.ReadFile(path); err == nil {
+			var header schemaHeader
+			if yaml.Unmarshal(content, &header) == nil {
+				info.Name = header.Schema.Name
+			}
+		}
+		_, info.Active = order[id]
+		schemas = append(schemas, info)
+	}
+
+	sort.SliceStable(schemas, func(i, j int) bool {
+		if schemas[i].Active != schemas[j].Active {
+			return schemas[i].Active
+		}
+		if schemas[i].Active {
+			return order[schemas[i].ID] < order[schemas[j].ID]
+		}
+		return schemas[i].ID < schemas[j].ID
+	})
+	return schemas, nil
+}
+
+// ActiveSchemaIDs returns the effective schema_list of the user directory:
+// the schema_list of default.yaml (from the user directory or the shared
+// data directory) with the patches in default.custom.yaml applied, or the
+// compiled copy under build/ when default.yaml itself is not available. It
+// returns nil when no schema_list can be found.
+func (m *RimeManager) ActiveSchemaIDs() []string {
+	patches := m.schemaListPatches()
+
+	// Patches after the last full replacement apply to its list alone
+	var list []schemaListEntry
+	found := false
+	for i := len(patches) - 1; i >= 0; i-- {
+		if patches[i].op == "" {
+			if patches[i].value.Decode(&list) != nil {
+				list = nil
+			}
+			patches, found = patches[i+1:], true
+			break
+		}
+	}
+
+	if !found {
+		var compiled bool
+		list, compiled, found = m.defaultSchemaList()
+		if compiled {
+			// build/default.yaml already has default.custom.yaml applied
+			return schemaIDs(list)
+		}
+		if !found && len(patches) == 0 {
+			return nil
+		}
+	}
+
+	for _, p := range patches {
+		list = p.apply(list)
+	}
+	return schemaIDs(list)
+}
+
+// schemaListPatch is one patch of default.custom.yaml that changes
+// schema_list, e.g. "schema_list/+" or "schema_list/@before 0".
+type schemaListPatch struct {
+	op    string // the part of the key after "schema_list", "" for a full replacement
+	value *yaml.Node
+}
+
+// apply returns list with the patch applied, following librime's rules:
+// "/+" appends a list, "/@next" appends an item, "/@before N" and
+// "/@after N" insert one, and "/@N" or "/@last" replace one. Patches that
+// cannot be applied leave the list unchanged.
+func (p schemaListPatch) apply(list []schemaListEntry) []schemaListEntry {
+	if p.op == "/+" {
+		var items []schemaListEntry
+		if p.value.Decode(&items) != nil {
+			return list
+		}
+		return append(list, items...)
+	}
+
+	var item schemaListEntry
+	if p.value.Decode(&item) != nil {
+		return list
+	}
+	switch op := strings.TrimSpace(strings.TrimPrefix(p.op, "/@")); {
+	case !strings.HasPrefix(p.op, "/@"):
+		return list
+	case op == "next":
+		return append(list, item)
+	case op == "last":
+		if len(list) > 0 {
+			list[len(list)-1] = item
+		}
+		return list
+	case strings.HasPrefix(op, "before ") || strings.HasPrefix(op, "after "):
+		where, index, _ := strings.Cut(op, " ")
+		n, ok := schemaListIndex(strings.TrimSpace(index), len(list))
+		if !ok {
+			return list
+		}
+		if where == "after" {
+			n++
+		}
+		if n > len(list) {
+			n = len(list)
+		}
+		return append(list[:n], append([]schemaListEntry{item}, list[n:]...)...)
+	default:
+		if n, ok := schemaListIndex(op, len(list)); ok && n < len(list) {
+			list[n] = item
+		}
+		return list
+	}
+}
+
+// schemaListIndex parses a list index of a patch key; "last" is the last item.
+func schemaListIndex(value string, length int) (int, bool) {
+	if value == "last" {
+		return max(length-1, 0), true
+	}
+	n, err := strconv.Atoi(value)
+	return n, err == nil && n >= 0
+}
+
+// schemaListPatches returns the schema_list patches of default.custom.yaml in
+// the order they appear.
+func (m *RimeManager) schemaListPatches() []schemaListPatch {
+	content, err := os.ReadFile(filepath.Join(m.UserDirectory, "default.custom.yaml"))
+	if err != nil {
+		return nil
+	}
+	var custom struct {
+		Patch yaml.Node `yaml:"patch"`
+	}
+	if yaml.Unmarshal(content, &custom) != nil || custom.Patch.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	var patches []schemaListPatch
+	for i := 0; i+1 < len(custom.Patch.Content); i += 2 {
+		key, value := custom.Patch.Content[i].Value, custom.Patch.Content[i+1]
+		if op, ok := strings.CutPrefix(key, "schema_list"); ok && (op == "" || strings.HasPrefix(op, "/")) {
+			patches = append(patches, schemaListPatch{op: op, value: value})
+		}
+	}
+	return patches
+}
+
+// defaultSchemaList reads schema_list from default.yaml in the user directory
+// or the shared data directory, else from the compiled build/default.yaml, in
+// which case compiled is true.
+func (m *RimeManager) defaultSchemaList() (list []schemaListEntry, compiled bool, found bool) {
+	var paths []string
+	paths = append(paths, filepath.Join(m.UserDirectory, "default.yaml"))
+	for _, dir := range sharedDataDirs {
+		paths = append(paths, filepath.Join(dir, "default.yaml"))
+	}
+	paths = append(paths, filepath.Join(m.UserDirectory, "build", "default.yaml"))
+
+	for i, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var def struct {
+			SchemaList []schemaListEntry `yaml:"schema_list"`
+		}
+		if yaml.Unmarshal(content, &def) == nil && len(def.SchemaList) > 0 {
+			return def.SchemaList, i == len(paths)-1, true
+		}
+	}
+	return nil, false, false
+}
+
+func schemaIDs(list []schemaListEntry) []string {
+	ids := make([]string, 0, len(list))
+	for _, e := range list {
+		if e.Schema != "" {
+			ids = append(ids, e.Schema)
+		}
+	}
+	return ids
+}
+
+// SchemaExists reports whether a schema can be targeted: its file is in the
+// user directory, or it has been deployed from elsewhere.
+func (m *RimeManager) SchemaExists(schemaID string) bool {
+	for _, path := range []string{m.GetSchemaPath(schemaID), m.GetBuildSchemaPath(schemaID)} {
+		if _, err := os.Stat(path); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// ConfiguredSchemas returns the IDs of discovered schemas that load the
+// logger processor, either from the schema file or from its custom patch.
+func (m *RimeManager) ConfiguredSchemas() ([]string, error) {
+	schemas, err := m.DiscoverSchemas()
+	if err != nil {
+		return nil, err
+	}
+	var configured []string
+	for _, s := range schemas {
+		if ok, err := m.CheckSchemaConfigured(s.ID); err == nil && ok {
+			configured = append(configured, s.ID)
+		} else if ok, err := m.CheckPatchConfigured(s.ID); err == nil && ok {
+			configured = append(configured, s.ID)
+		}
+	}
+	return configured, nil
+}
@@ -0,0 +1,191 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrUnknownVersion is returned when a logger script has no recognisable version header.
+var ErrUnknownVersion = errors.New("no version header found")
+
+// loggerVersionRegex matches the header comment of input_habit_logger.lua,
+// e.g. "-- 输入习惯记录器 (Version 14.1 - V2.2 Incremental Update)".
+var loggerVersionRegex = regexp.MustCompile(`Version\s+(\d+(?:\.\d+)*)\s*-\s*V(\d+(?:\.\d+)*)`)
+
+// LoggerVersion is the version of a logger script: the base script version
+// and the revision layered on top of it ("14.1 - V2.2").
+type LoggerVersion struct {
+	Base     string `json:"base" yaml:"base"`
+	Revision string `json:"revision" yaml:"revision"`
+}
+
+// String formats the version as it appears in the script header.
+func (v LoggerVersion) String() string {
+	return v.Base + " - V" + v.Revision
+}
+
+// Compare returns -1, 0 or 1 depending on whether v is older than, the same
+// as, or newer than other. Components are compared numerically.
+func (v LoggerVersion) Compare(other LoggerVersion) int {
+	if c := compareDotted(v.Base, other.Base); c != 0 {
+		return c
+	}
+	return compareDotted(v.Revision, other.Revision)
+}
+
+func compareDotted(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	}
+	return 0
+}
+
+// ParseLoggerVersion reads the version header from the first lines of a logger script.
+func ParseLoggerVersion(script []byte) (LoggerVersion, error) {
+	head := script
+	for i, n := 0, 0; i < len(head); i++ {
+		if head[i] == '\n' {
+			if n++; n == 5 {
+				head = head[:i]
+				break
+			}
+		}
+	}
+	match := loggerVersionRegex.FindSubmatch(head)
+	if match == nil {
+		return LoggerVersion{}, ErrUnknownVersion
+	}
+	return LoggerVersion{Base: string(match[1]), Revision: string(match[2])}, nil
+}
+
+// InstalledLoggerVersion reads the version header of the installed input_habit_logger.lua.
+func (m *RimeManager) InstalledLoggerVersion() (LoggerVersion, error) {
+	content, err := os.ReadFile(filepath.Join(m.LuaDirectory, LoggerLuaFile))
+	if err != nil {
+		return LoggerVersion{}, err
+	}
+	return ParseLoggerVersion(content)
+}
+
+// ConfigSetting is one value of input_habit_logger_config.lua, keyed by its
+// dotted table path (e.g. "presets.custom.log_events.error") and holding the
+// Lua literal as written.
+type ConfigSetting struct {
+	Key   string `json:"key" yaml:"key"`
+	Value string `json:"value" yaml:"value"`
+}
+
+// ConfigMigration describes what MigrateConfig carried over from the old config.
+type ConfigMigration struct {
+	// Carried are the user's settings written into the new template.
+	Carried []ConfigSetting `json:"carried" yaml:"carried"`
+	// Dropped are settings outside the presets and those the new template
+	// has no table for.
+	Dropped []ConfigSetting `json:"dropped,omitempty" yaml:"dropped,omitempty"`
+}
+
+// migratedSetting reports whether a config key belongs to the user and is
+// carried over by an upgrade: the preset choice and every setting of every
+// preset, since 'config set' edits whichever preset is active.
+func migratedSetting(path []string) bool {
+	if len(path) == 1 {
+		return path[0] == "preset_choice"
+	}
+	return len(path) > 2 && path[0] == "presets"
+}
+
+// MigrateConfig rewrites template, the config shipped with a new logger
+// version, so that it keeps the user's settings from old. Only the values
+// change; the template's comments and layout are kept. Settings missing from
+// the template are added to their table when it exists, else dropped.
+func MigrateConfig(old, template []byte) ([]byte, *ConfigMigration, error) {
+	oldChunk, err := parseLua(old)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not read existing config: %w", err)
+	}
+	newChunk, err := parseLua(template)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not read config template: %w", err)
+	}
+
+	migration := &ConfigMigration{}
+	var edits []luaEdit
+	for _, s := range oldChunk.effectiveSettings() {
+		if s.isTable() {
+			continue
+		}
+		value := oldChunk.text(s.expr)
+		setting := ConfigSetting{Key: s.key(), Value: value}
+		if !migratedSetting(s.path) {
+			migration.Dropped = append(migration.Dropped, setting)
+			continue
+		}
+
+		if target, ok := newChunk.setting(s.path); ok {
+			switch {
+			case target.isTable():
+				migration.Dropped = append(migration.Dropped, setting)
+			case newChunk.text(target.expr) != value:
+				edits = append(edits, replaceEdit(target, value))
+				migration.Carried = append(migration.Carried, setting)
+			}
+			continue
+		}
+
+		parent, ok := newChunk.setting(s.path[:len(s.path)-1])
+		if !ok || !parent.isTable() {
+			migration.Dropped = append(migration.Dropped, setting)
+			continue
+		}
+		edits = append(edits, newChunk.insertFieldEdit(parent.expr, s.path[len(s.path)-1], value))
+		migration.Carried = append(migration.Carried, setting)
+	}
+
+	migrated, err := applyLuaEdits(newChunk.src, edits)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not migrate config: %w", err)
+	}
+	return []byte(migrated), migration, nil
+}
+
+// PlanUpgrade plans replacing the installed logger script with script and the
+// config with configTemplate, migrated by MigrateConfig. When no config is
+// installed, the template is written as is and the migration is empty.
+func (m *RimeManager) PlanUpgrade(p *Plan, script, configTemplate []byte) (*ConfigMigration, error) {
+	if err := p.Write(filepath.Join(m.LuaDirectory, LoggerLuaFile), script); err != nil {
+		return nil, err
+	}
+
+	configPath := filepath.Join(m.LuaDirectory, ConfigLuaFile)
+	old, exists, err := p.Content(configPath)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return &ConfigMigration{}, p.Write(configPath, configTemplate)
+	}
+
+	migrated, migration, err := MigrateConfig(old, configTemplate)
+	if err != nil {
+		return nil, err
+	}
+	return migration, p.Write(configPath, migrated)
+}
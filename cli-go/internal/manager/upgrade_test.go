@@ -0,0 +1,185 @@
+package manager
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"rime-wanxiang-logger-go/internal/assets"
+)
+
+func TestParseLoggerVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		want    LoggerVersion
+		wantErr error
+	}{
+		{"header", "-- 输入习惯记录器 (Version 14.1 - V2.2 Incremental Update)\nlocal x = 1\n", LoggerVersion{"14.1", "2.2"}, nil},
+		{"CRLF", "-- 输入习惯记录器 (Version 9 - V1)\r\nlocal x = 1\r\n", LoggerVersion{"9", "1"}, nil},
+		{"no header", "local x = 1\n", LoggerVersion{}, ErrUnknownVersion},
+		{"header too far down", strings.Repeat("--\n", 6) + "-- Version 1.0 - V1.0\n", LoggerVersion{}, ErrUnknownVersion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLoggerVersion([]byte(tt.script))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := ParseLoggerVersion(assets.LoggerScript); err != nil {
+		t.Errorf("embedded logger script: %v", err)
+	}
+}
+
+func TestLoggerVersionCompare(t *testing.T) {
+	tests := []struct {
+		a, b LoggerVersion
+		want int
+	}{
+		{LoggerVersion{"14.1", "2.2"}, LoggerVersion{"14.1", "2.2"}, 0},
+		{LoggerVersion{"14.1", "2.2"}, LoggerVersion{"14.1", "2.10"}, -1},
+		{LoggerVersion{"14.10", "1"}, LoggerVersion{"14.9", "9"}, 1},
+		{LoggerVersion{"14", "2"}, LoggerVersion{"14.0", "2.0"}, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.a.Compare(tt.b); got != tt.want {
+			t.Errorf("%v.Compare(%v) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestMigrateConfig(t *testing.T) {
+	// An older config with the user's changes, including syntax the template does not use
+	old := `-- 旧版配置
+local preset_choice = 'custom'
+local presets = {
+    normal = { enabled = true, log_events = { input_state_changed = true } }, -- changed with 'config set'
+    developer = {
+        log_file_path = [[D:\logs\dev.jsonl]],
+    },
+    custom = {
+        enabled = false, -- 暂停记录
+        log_only_non_first_choice = true,
+        log_file_path = "C:\\rime\\custom.jsonl",
+        log_events = { input_state_changed = true, },
+        log_fields = {
+            text_committed = { source_candidates_list = true },
+        },
+        retired_option = 5,
+    },
+}
+return presets[preset_choice] or presets.custom
+`
+
+	migrated, migration, err := MigrateConfig([]byte(old), assets.ConfigScript)
+	if err != nil {
+		t.Fatalf("MigrateConfig: %v", err)
+	}
+
+	config, err := ParseLoggerConfig(migrated)
+	if err != nil {
+		t.Fatalf("migrated config does not evaluate: %v", err)
+	}
+	if config.Preset != "custom" || config.Enabled || !config.LogOnlyNonFirstChoice {
+		t.Errorf("custom preset not carried over: %+v", config)
+	}
+	if config.LogFilePath != `C:\rime\custom.jsonl` {
+		t.Errorf("LogFilePath = %q", config.LogFilePath)
+	}
+	if !config.LogEvents["input_state_changed"] || !config.LogFields["text_committed"]["source_candidates_list"] {
+		t.Errorf("nested custom settings not carried over: %+v", config)
+	}
+
+	carried := make(map[string]string)
+	for _, s := range migration.Carried {
+		carried[s.Key] = s.Value
+	}
+	for key, value := range map[string]string{
+		"preset_choice":                                 `'custom'`,
+		"presets.developer.log_file_path":               `[[D:\logs\dev.jsonl]]`,
+		"presets.custom.enabled":                        "false",
+		"presets.custom.retired_option":                 "5", // inserted into the custom table
+		"presets.normal.log_events.input_state_changed": "true",
+	} {
+		if carried[key] != value {
+			t.Errorf("carried[%s] = %q, want %q", key, carried[key], value)
+		}
+	}
+	if _, ok := carried["presets.normal.enabled"]; ok {
+		t.Error("a value equal to the template's must not be reported as carried")
+	}
+
+	// The template's comments survive; only values change
+	for _, line := range strings.Split(string(assets.ConfigScript), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, "--") && !strings.Contains(string(migrated), trimmed) {
+			t.Errorf("template comment lost: %q", trimmed)
+		}
+	}
+}
+
+func TestMigrateConfigDropsUnknownTables(t *testing.T) {
+	old := `local preset_choice = "normal"
+local verbose = true
+local presets = {
+    custom = { plugins = { extra = true } },
+}
+return presets[preset_choice] or presets.custom
+`
+	_, migration, err := MigrateConfig([]byte(old), assets.ConfigScript)
+	if err != nil {
+		t.Fatalf("MigrateConfig: %v", err)
+	}
+	var dropped []string
+	for _, s := range migration.Dropped {
+		dropped = append(dropped, s.Key)
+	}
+	if want := []string{"verbose", "presets.custom.plugins.extra"}; !slices.Equal(dropped, want) {
+		t.Errorf("Dropped = %v, want %v", dropped, want)
+	}
+}
+
+func TestMigrateConfigUnchanged(t *testing.T) {
+	migrated, migration, err := MigrateConfig(assets.ConfigScript, assets.ConfigScript)
+	if err != nil {
+		t.Fatalf("MigrateConfig: %v", err)
+	}
+	if string(migrated) != string(assets.ConfigScript) || len(migration.Carried) != 0 || len(migration.Dropped) != 0 {
+		t.Errorf("migrating the template onto itself changed it: %+v", migration)
+	}
+}
+
+func TestMigrateConfigLastAssignmentWins(t *testing.T) {
+	old := `local preset_choice = "custom"
+local presets = {
+    custom = { log_file_path = "a.jsonl", enabled = false },
+}
+presets.custom.log_file_path = "b.jsonl"
+presets.developer = { log_file_path = "dev-a.jsonl" }
+presets.developer = { log_only_non_first_choice = true }
+return presets[preset_choice] or presets.custom
+`
+	migrated, migration, err := MigrateConfig([]byte(old), assets.ConfigScript)
+	if err != nil {
+		t.Fatalf("MigrateConfig: %v", err)
+	}
+	config, err := ParseLoggerConfig(migrated)
+	if err != nil {
+		t.Fatalf("migrated config does not evaluate: %v", err)
+	}
+	if config.LogFilePath != "b.jsonl" || config.Enabled {
+		t.Errorf("custom preset = %+v, want the last log_file_path and enabled = false", config)
+	}
+	for _, s := range migration.Carried {
+		if s.Key == "presets.developer.log_file_path" {
+			t.Errorf("carried %s from a table that was replaced later", s.Key)
+		}
+	}
+}
@@ -0,0 +1,366 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProcessorPosition chooses where a processor is inserted into engine/processors.
+type ProcessorPosition struct {
+	// Anchor is an existing processor to insert next to. Empty means the
+	// start (Before) or the end of the list.
+	Anchor string
+	// Before inserts in front of Anchor instead of after it.
+	Before bool
+}
+
+// DefaultProcessorPosition places the logger right after the punctuator, so it
+// sees the keys the selector and navigator act on, like the original installer.
+var DefaultProcessorPosition = ProcessorPosition{Anchor: "punctuator"}
+
+// ErrProcessorsNotFound is returned when a file has no engine/processors list to edit.
+var ErrProcessorsNotFound = errors.New("could not find engine/processors list")
+
+// ParseProcessorPosition parses "first", "last", "after:<processor>" or "before:<processor>".
+func ParseProcessorPosition(value string) (ProcessorPosition, error) {
+	switch value {
+	case "", "after:punctuator":
+		return DefaultProcessorPosition, nil
+	case "first":
+		return ProcessorPosition{Before: true}, nil
+	case "last":
+		return ProcessorPosition{}, nil
+	}
+
+	where, anchor, ok := strings.Cut(value, ":")
+	anchor = strings.TrimSpace(anchor)
+	if ok && anchor != "" {
+		switch where {
+		case "after":
+			return ProcessorPosition{Anchor: anchor}, nil
+		case "before":
+			return ProcessorPosition{Anchor: anchor, Before: true}, nil
+		}
+	}
+	return ProcessorPosition{}, fmt.Errorf("invalid position %q (expected first, last, after:<processor> or before:<processor>)", value)
+}
+
+// String returns the position in the form accepted by ParseProcessorPosition.
+func (p ProcessorPosition) String() string {
+	switch {
+	case p.Anchor == "" && p.Before:
+		return "first"
+	case p.Anchor == "":
+		return "last"
+	case p.Before:
+		return "before:" + p.Anchor
+	default:
+		return "after:" + p.Anchor
+	}
+}
+
+// yamlSource is a YAML file parsed for in-place editing: the node tree is
+// used to locate what to change, and the change is applied to the original
+// lines so that comments, quoting and indentation elsewhere survive.
+type yamlSource struct {
+	doc   yaml.Node
+	lines []string
+}
+
+func parseYAMLSource(content []byte) (*yamlSource, error) {
+	src := &yamlSource{lines: strings.Split(string(content), "\n")}
+	if err := yaml.Unmarshal(content, &src.doc); err != nil {
+		return nil, err
+	}
+	return src, nil
+}
+
+// root returns the top-level mapping, or nil.
+func (s *yamlSource) root() *yaml.Node {
+	return documentMapping(&s.doc)
+}
+
+func (s *yamlSource) bytes() []byte {
+	return []byte(strings.Join(s.lines, "\n"))
+}
+
+// newline returns the line ending used by the file, "\r\n" or "\n".
+func (s *yamlSource) newline() string {
+	if len(s.lines) > 1 && strings.HasSuffix(s.lines[0], "\r") {
+		return "\r\n"
+	}
+	return "\n"
+}
+
+// insertLine inserts text as line index i (0-based), keeping CRLF endings consistent.
+func (s *yamlSource) insertLine(i int, text string) {
+	if i > 0 && strings.HasSuffix(s.lines[i-1], "\r") {
+		text += "\r"
+	}
+	s.lines = append(s.lines[:i], append([]string{text}, s.lines[i:]...)...)
+}
+
+func (s *yamlSource) deleteLine(i int) {
+	s.lines = append(s.lines[:i], s.lines[i+1:]...)
+}
+
+// editLine replaces the runes [from, to) of line index i with text.
+func (s *yamlSource) editLine(i, from, to int, text string) {
+	line := []rune(s.lines[i])
+	s.lines[i] = string(line[:from]) + text + string(line[to:])
+}
+
+// documentMapping returns the top-level mapping of a parsed document, or nil.
+func documentMapping(doc *yaml.Node) *yaml.Node {
+	if doc == nil || doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil
+	}
+	if root := doc.Content[0]; root.Kind == yaml.MappingNode {
+		return root
+	}
+	return nil
+}
+
+// mappingEntry returns the key and value nodes of key in mapping, or nils.
+func mappingEntry(mapping *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i], mapping.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// containsScalar reports whether any scalar below node equals value.
+func containsScalar(node *yaml.Node, value string) bool {
+	if node == nil {
+		return false
+	}
+	if node.Kind == yaml.ScalarNode {
+		return node.Value == value
+	}
+	for _, child := range node.Content {
+		if containsScalar(child, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaProcessors returns the engine/processors sequence of a schema file.
+func schemaProcessors(root *yaml.Node) *yaml.Node {
+	_, engine := mappingEntry(root, "engine")
+	_, processors := mappingEntry(engine, "processors")
+	return processors
+}
+
+// patchProcessors returns an engine/processors list replaced wholesale by a
+// patch, written either as "engine/processors:" or nested under "engine:".
+func patchProcessors(patch *yaml.Node) *yaml.Node {
+	if _, processors := mappingEntry(patch, "engine/processors"); processors != nil {
+		return processors
+	}
+	return schemaProcessors(patch)
+}
+
+// processorMatches reports whether a processor entry is name, either exactly
+// or as a named instance such as "punctuator@custom".
+func processorMatches(entry, name string) bool {
+	return entry == name || strings.HasPrefix(entry, name+"@")
+}
+
+// sequenceIndex returns the index of the first scalar item equal to value, or -1.
+func sequenceIndex(seq *yaml.Node, match func(string) bool) int {
+	if seq == nil || seq.Kind != yaml.SequenceNode {
+		return -1
+	}
+	for i, item := range seq.Content {
+		if item.Kind == yaml.ScalarNode && match(item.Value) {
+			return i
+		}
+	}
+	return -1
+}
+
+// insertProcessor adds entry to seq at pos. comment is appended as a trailing
+// "#" comment in block sequences. It reports false if entry is already present.
+func (s *yamlSource) insertProcessor(seq *yaml.Node, entry, comment string, pos ProcessorPosition) (bool, error) {
+	if seq == nil || seq.Kind != yaml.SequenceNode {
+		return false, ErrProcessorsNotFound
+	}
+	if sequenceIndex(seq, func(v string) bool { return v == entry }) >= 0 {
+		return false, nil
+	}
+
+	items := seq.Content
+	flow := seq.Style&yaml.FlowStyle != 0
+	if len(items) == 0 {
+		if !flow {
+			return false, ErrProcessorsNotFound
+		}
+		// "[]": insert right after the opening bracket
+		s.editLine(seq.Line-1, seq.Column, seq.Column, entry)
+		return true, nil
+	}
+
+	index := len(items) - 1
+	before := pos.Before
+	switch {
+	case pos.Anchor != "":
+		index = sequenceIndex(seq, func(v string) bool { return processorMatches(v, pos.Anchor) })
+		if index < 0 {
+			return false, fmt.Errorf("could not find %q in engine/processors", pos.Anchor)
+		}
+	case before:
+		index = 0
+	}
+	item := items[index]
+
+	if flow {
+		if before {
+			s.editLine(item.Line-1, item.Column-1, item.Column-1, entry+", ")
+		} else {
+			end := s.flowItemEnd(item)
+			s.editLine(item.Line-1, end, end, ", "+entry)
+		}
+		return true, nil
+	}
+
+	dash, ok := s.itemDash(item)
+	if !ok {
+		return false, fmt.Errorf("unsupported layout of engine/processors at line %d", item.Line)
+	}
+	text := strings.Repeat(" ", dash) + "- " + entry
+	if comment != "" {
+		text += " #" + comment
+	}
+
+	if before {
+		at := item.Line - 1
+		// Keep comments directly above the item attached to it
+		for at > 0 && strings.HasPrefix(strings.TrimSpace(s.lines[at-1]), "#") {
+			at--
+		}
+		s.insertLine(at, text)
+	} else {
+		s.insertLine(lastLine(item), text)
+	}
+	return true, nil
+}
+
+// removeProcessor deletes every scalar item equal to entry from the
+// sequence found by locate. It reports whether anything was removed.
+func (s *yamlSource) removeProcessor(locate func(*yamlSource) *yaml.Node, entry string) (bool, error) {
+	removed := false
+	for {
+		seq := locate(s)
+		index := sequenceIndex(seq, func(v string) bool { return v == entry })
+		if index < 0 {
+			return removed, nil
+		}
+		item := seq.Content[index]
+
+		if seq.Style&yaml.FlowStyle != 0 {
+			s.removeFlowItem(item)
+		} else {
+			if _, ok := s.itemDash(item); !ok || lastLine(item) != item.Line {
+				return removed, fmt.Errorf("unsupported layout of engine/processors at line %d", item.Line)
+			}
+			s.deleteLine(item.Line - 1)
+		}
+		removed = true
+
+		// Line and column information is stale after an edit
+		s.doc = yaml.Node{}
+		if err := yaml.Unmarshal(s.bytes(), &s.doc); err != nil {
+			return removed, fmt.Errorf("edit produced invalid YAML: %w", err)
+		}
+	}
+}
+
+// itemDash returns the rune column of the "-" introducing a block sequence
+// item, checking that only indentation precedes it.
+func (s *yamlSource) itemDash(item *yaml.Node) (int, bool) {
+	line := []rune(s.lines[item.Line-1])
+	for i := item.Column - 2; i >= 0 && i < len(line); i-- {
+		if line[i] == '-' {
+			return i, strings.TrimSpace(string(line[:i])) == ""
+		}
+		if line[i] != ' ' {
+			break
+		}
+	}
+	return 0, false
+}
+
+// flowItemEnd returns the rune column just past a scalar item in a flow sequence.
+func (s *yamlSource) flowItemEnd(item *yaml.Node) int {
+	line := []rune(s.lines[item.Line-1])
+	end := item.Column - 1
+	quote := rune(0)
+	if item.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0 {
+		quote = line[end]
+		end++
+	}
+	for ; end < len(line); end++ {
+		c := line[end]
+		if quote != 0 {
+			if c == quote {
+				return end + 1
+			}
+			continue
+		}
+		if c == ',' || c == ']' || c == '#' {
+			break
+		}
+	}
+	for end > item.Column-1 && line[end-1] == ' ' {
+		end--
+	}
+	return end
+}
+
+// removeFlowItem deletes a scalar item and one adjacent comma from a flow sequence.
+func (s *yamlSource) removeFlowItem(item *yaml.Node) {
+	i := item.Line - 1
+	line := []rune(s.lines[i])
+	from, to := item.Column-1, s.flowItemEnd(item)
+
+	// Prefer swallowing the following comma; for the last item take the preceding one
+	next := to
+	for next < len(line) && line[next] == ' ' {
+		next++
+	}
+	if next < len(line) && line[next] == ',' {
+		to = next + 1
+		for to < len(line) && line[to] == ' ' {
+			to++
+		}
+	} else {
+		prev := from
+		for prev > 0 && line[prev-1] == ' ' {
+			prev--
+		}
+		if prev > 0 && line[prev-1] == ',' {
+			from = prev - 1
+		}
+	}
+	s.editLine(i, from, to, "")
+}
+
+// lastLine returns the 1-based number of the last line spanned by node.
+func lastLine(node *yaml.Node) int {
+	last := node.Line
+	for _, child := range node.Content {
+		if l := lastLine(child); l > last {
+			last = l
+		}
+	}
+	return last
+}
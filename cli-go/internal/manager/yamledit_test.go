@@ -0,0 +1,434 @@
+package manager
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// loggerLine is the block list item insertProcessor writes for the logger.
+const loggerLine = "- " + LoggerProcessor + " #" + LoggerComment
+
+func insertLogger(t *testing.T, content string, pos ProcessorPosition) (string, bool, error) {
+	t.Helper()
+	src, err := parseYAMLSource([]byte(content))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	changed, err := src.insertProcessor(schemaProcessors(src.root()), LoggerProcessor, LoggerComment, pos)
+	return string(src.bytes()), changed, err
+}
+
+func removeLogger(t *testing.T, content string) (string, bool, error) {
+	t.Helper()
+	src, err := parseYAMLSource([]byte(content))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	removed, err := src.removeProcessor(func(s *yamlSource) *yaml.Node {
+		return schemaProcessors(s.root())
+	}, LoggerProcessor)
+	return string(src.bytes()), removed, err
+}
+
+func TestInsertProcessor(t *testing.T) {
+	first := ProcessorPosition{Before: true}
+	last := ProcessorPosition{}
+
+	tests := []struct {
+		name    string
+		content string
+		pos     ProcessorPosition
+		want    string
+	}{
+		{
+			name: "block list after punctuator",
+			content: "engine:\n" +
+				"  processors:\n" +
+				"    - ascii_composer\n" +
+				"    - punctuator\n" +
+				"    - selector\n",
+			pos: DefaultProcessorPosition,
+			want: "engine:\n" +
+				"  processors:\n" +
+				"    - ascii_composer\n" +
+				"    - punctuator\n" +
+				"    " + loggerLine + "\n" +
+				"    - selector\n",
+		},
+		{
+			name: "block list without indentation",
+			content: "engine:\n" +
+				"  processors:\n" +
+				"  - punctuator\n" +
+				"  - selector\n",
+			pos: last,
+			want: "engine:\n" +
+				"  processors:\n" +
+				"  - punctuator\n" +
+				"  - selector\n" +
+				"  " + loggerLine + "\n",
+		},
+		{
+			name: "keeps comments above the first item",
+			content: "engine:\n" +
+				"  processors:\n" +
+				"    # handles ascii mode\n" +
+				"    # keep it first\n" +
+				"    - ascii_composer\n" +
+				"    - punctuator\n",
+			pos: first,
+			want: "engine:\n" +
+				"  processors:\n" +
+				"    " + loggerLine + "\n" +
+				"    # handles ascii mode\n" +
+				"    # keep it first\n" +
+				"    - ascii_composer\n" +
+				"    - punctuator\n",
+		},
+		{
+			name: "keeps comments above and after the anchor",
+			content: "engine:\n" +
+				"  processors:\n" +
+				"    - ascii_composer\n" +
+				"    # punctuation\n" +
+				"    - punctuator # full width\n" +
+				"    # selection\n" +
+				"    - selector\n",
+			pos: DefaultProcessorPosition,
+			want: "engine:\n" +
+				"  processors:\n" +
+				"    - ascii_composer\n" +
+				"    # punctuation\n" +
+				"    - punctuator # full width\n" +
+				"    " + loggerLine + "\n" +
+				"    # selection\n" +
+				"    - selector\n",
+		},
+		{
+			name: "punctuator in a comment is not the anchor",
+			content: "# the punctuator goes last\n" +
+				"engine:\n" +
+				"  processors:\n" +
+				"    - ascii_composer # not punctuator\n" +
+				"    - punctuator\n",
+			pos: DefaultProcessorPosition,
+			want: "# the punctuator goes last\n" +
+				"engine:\n" +
+				"  processors:\n" +
+				"    - ascii_composer # not punctuator\n" +
+				"    - punctuator\n" +
+				"    " + loggerLine + "\n",
+		},
+		{
+			name: "named instance anchor",
+			content: "engine:\n" +
+				"  processors:\n" +
+				"    - punctuator@custom\n" +
+				"    - selector\n",
+			pos: DefaultProcessorPosition,
+			want: "engine:\n" +
+				"  processors:\n" +
+				"    - punctuator@custom\n" +
+				"    " + loggerLine + "\n" +
+				"    - selector\n",
+		},
+		{
+			name: "before a named lua processor",
+			content: "engine:\n" +
+				"  processors:\n" +
+				"    - lua_processor@*other_logger\n" +
+				"    - selector\n",
+			pos: ProcessorPosition{Anchor: "lua_processor@*other_logger", Before: true},
+			want: "engine:\n" +
+				"  processors:\n" +
+				"    " + loggerLine + "\n" +
+				"    - lua_processor@*other_logger\n" +
+				"    - selector\n",
+		},
+		{
+			name:    "flow list after punctuator",
+			content: "engine:\n  processors: [ascii_composer, punctuator, selector]\n",
+			pos:     DefaultProcessorPosition,
+			want:    "engine:\n  processors: [ascii_composer, punctuator, " + LoggerProcessor + ", selector]\n",
+		},
+		{
+			name:    "flow list first with quoted items",
+			content: "engine:\n  processors: [\"ascii_composer\", 'punctuator'] # trailing\n",
+			pos:     first,
+			want:    "engine:\n  processors: [" + LoggerProcessor + ", \"ascii_composer\", 'punctuator'] # trailing\n",
+		},
+		{
+			name:    "flow list last with quoted item",
+			content: "engine:\n  processors: [ascii_composer, \"punctuator\" ]\n",
+			pos:     last,
+			want:    "engine:\n  processors: [ascii_composer, \"punctuator\", " + LoggerProcessor + " ]\n",
+		},
+		{
+			name:    "empty flow list",
+			content: "engine:\n  processors: []\n",
+			pos:     DefaultProcessorPosition,
+			want:    "engine:\n  processors: [" + LoggerProcessor + "]\n",
+		},
+		{
+			name: "CRLF block list",
+			content: "engine:\r\n" +
+				"  processors:\r\n" +
+				"    # comment\r\n" +
+				"    - punctuator\r\n" +
+				"    - selector\r\n",
+			pos: DefaultProcessorPosition,
+			want: "engine:\r\n" +
+				"  processors:\r\n" +
+				"    # comment\r\n" +
+				"    - punctuator\r\n" +
+				"    " + loggerLine + "\r\n" +
+				"    - selector\r\n",
+		},
+		{
+			name:    "CRLF flow list",
+			content: "engine:\r\n  processors: [punctuator, selector]\r\n",
+			pos:     first,
+			want:    "engine:\r\n  processors: [" + LoggerProcessor + ", punctuator, selector]\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changed, err := insertLogger(t, tt.content, tt.pos)
+			if err != nil {
+				t.Fatalf("insertProcessor: %v", err)
+			}
+			if !changed {
+				t.Fatal("insertProcessor reported no change")
+			}
+			if got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+			var doc yaml.Node
+			if err := yaml.Unmarshal([]byte(got), &doc); err != nil {
+				t.Errorf("result is not valid YAML: %v", err)
+			}
+
+			// Inserting again is a no-op, and removing restores the original
+			again, changed, err := insertLogger(t, got, tt.pos)
+			if err != nil || changed || again != got {
+				t.Errorf("second insert: changed=%v err=%v", changed, err)
+			}
+			restored, removed, err := removeLogger(t, got)
+			if err != nil || !removed {
+				t.Fatalf("removeProcessor: removed=%v err=%v", removed, err)
+			}
+			if restored != tt.content {
+				t.Errorf("after removal got:\n%s\nwant:\n%s", restored, tt.content)
+			}
+		})
+	}
+}
+
+func TestInsertProcessorErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		pos     ProcessorPosition
+		wantErr error
+	}{
+		{
+			name:    "missing processors",
+			content: "engine:\n  segmentors:\n    - abc_segmentor\n",
+			wantErr: ErrProcessorsNotFound,
+		},
+		{
+			name:    "missing engine",
+			content: "schema:\n  schema_id: test\n",
+			wantErr: ErrProcessorsNotFound,
+		},
+		{
+			name:    "processors without items",
+			content: "engine:\n  processors:\n  segmentors: []\n",
+			wantErr: ErrProcessorsNotFound,
+		},
+		{
+			name:    "processors is a mapping",
+			content: "engine:\n  processors:\n    punctuator: true\n",
+			wantErr: ErrProcessorsNotFound,
+		},
+		{
+			name:    "empty file",
+			content: "",
+			wantErr: ErrProcessorsNotFound,
+		},
+		{
+			name:    "anchor not found",
+			content: "engine:\n  processors:\n    - selector\n",
+			pos:     DefaultProcessorPosition,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changed, err := insertLogger(t, tt.content, tt.pos)
+			if err == nil {
+				t.Fatalf("expected an error, got:\n%s", got)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("got error %v, want %v", err, tt.wantErr)
+			}
+			if changed || got != tt.content {
+				t.Errorf("file changed on error:\n%s", got)
+			}
+		})
+	}
+}
+
+func TestRemoveProcessor(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		removed bool
+	}{
+		{
+			name: "only the exact named instance",
+			content: "engine:\n" +
+				"  processors:\n" +
+				"    - lua_processor@*input_habit_logger_extra\n" +
+				"    - " + LoggerProcessor + "\n" +
+				"    - lua_processor@*other\n",
+			want: "engine:\n" +
+				"  processors:\n" +
+				"    - lua_processor@*input_habit_logger_extra\n" +
+				"    - lua_processor@*other\n",
+			removed: true,
+		},
+		{
+			name: "duplicate entries",
+			content: "engine:\n" +
+				"  processors:\n" +
+				"    - " + LoggerProcessor + "\n" +
+				"    - punctuator\n" +
+				"    - " + LoggerProcessor + " # again\n",
+			want: "engine:\n" +
+				"  processors:\n" +
+				"    - punctuator\n",
+			removed: true,
+		},
+		{
+			name:    "last flow item",
+			content: "engine:\n  processors: [punctuator, " + LoggerProcessor + "]\n",
+			want:    "engine:\n  processors: [punctuator]\n",
+			removed: true,
+		},
+		{
+			name:    "only flow item",
+			content: "engine:\n  processors: [ \"" + LoggerProcessor + "\" ]\n",
+			want:    "engine:\n  processors: [  ]\n",
+			removed: true,
+		},
+		{
+			name:    "logger only mentioned in a comment",
+			content: "# " + LoggerProcessor + "\nengine:\n  processors:\n    - punctuator\n",
+			want:    "# " + LoggerProcessor + "\nengine:\n  processors:\n    - punctuator\n",
+		},
+		{
+			name:    "missing processors",
+			content: "engine:\n  processors:\n",
+			want:    "engine:\n  processors:\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, removed, err := removeLogger(t, tt.content)
+			if err != nil {
+				t.Fatalf("removeProcessor: %v", err)
+			}
+			if removed != tt.removed {
+				t.Errorf("removed = %v, want %v", removed, tt.removed)
+			}
+			if got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddLoggerPatch(t *testing.T) {
+	entry := "  " + patchLineToAdd
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "new file",
+			content: "",
+			want:    "# encoding: utf-8\n\npatch:\n" + entry + "\n",
+		},
+		{
+			name:    "no patch key",
+			content: "# user settings\nfoo: 1",
+			want:    "# user settings\nfoo: 1\n\npatch:\n" + entry + "\n",
+		},
+		{
+			name:    "no patch key with CRLF",
+			content: "# user settings\r\nfoo: 1\r\n",
+			want:    "# user settings\r\nfoo: 1\r\n\r\npatch:\r\n" + entry + "\r\n",
+		},
+		{
+			name:    "empty patch",
+			content: "patch:\n",
+			want:    "patch:\n" + entry + "\n",
+		},
+		{
+			name:    "existing patches keep their comments",
+			content: "patch:\n    # menu size\n    menu/page_size: 9\n",
+			want:    "patch:\n    " + patchLineToAdd + "\n    # menu size\n    menu/page_size: 9\n",
+		},
+		{
+			name:    "existing patches with CRLF",
+			content: "patch:\r\n  menu/page_size: 9\r\n",
+			want:    "patch:\r\n" + entry + "\r\n  menu/page_size: 9\r\n",
+		},
+		{
+			name:    "patch replacing processors as a flow list",
+			content: "patch:\n  engine/processors: [punctuator, selector]\n",
+			want:    "patch:\n  engine/processors: [punctuator, " + LoggerProcessor + ", selector]\n",
+		},
+		{
+			name:    "patch replacing processors as a block list",
+			content: "patch:\n  engine:\n    processors:\n      - punctuator\n",
+			want:    "patch:\n  engine:\n    processors:\n      - punctuator\n      " + loggerLine + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changed, err := addLoggerPatch([]byte(tt.content), DefaultProcessorPosition)
+			if err != nil {
+				t.Fatalf("addLoggerPatch: %v", err)
+			}
+			if !changed {
+				t.Fatal("addLoggerPatch reported no change")
+			}
+			if string(got) != tt.want {
+				t.Errorf("got:\n%q\nwant:\n%q", got, tt.want)
+			}
+
+			again, changed, err := addLoggerPatch(got, DefaultProcessorPosition)
+			if err != nil || changed || string(again) != string(got) {
+				t.Errorf("second add: changed=%v err=%v", changed, err)
+			}
+			removed, changed, err := removeLoggerPatch(got)
+			if err != nil || !changed {
+				t.Fatalf("removeLoggerPatch: changed=%v err=%v", changed, err)
+			}
+			if strings.Contains(string(removed), LoggerProcessor) {
+				t.Errorf("logger still present after removal:\n%s", removed)
+			}
+		})
+	}
+}
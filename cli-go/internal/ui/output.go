@@ -0,0 +1,183 @@
+package ui
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"gopkg.in/yaml.v3"
+)
+
+// Format selects how commands present their results.
+type Format string
+
+// Supported output formats. FormatText is the default colored, human-oriented output.
+const (
+	FormatText Format = "text"
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+	FormatCSV  Format = "csv"
+)
+
+var (
+	currentFormat           = FormatText
+	out           io.Writer = os.Stdout
+	errOut        io.Writer = os.Stderr
+)
+
+// ParseFormat validates a user-supplied --format value.
+func ParseFormat(value string) (Format, error) {
+	switch f := Format(strings.ToLower(value)); f {
+	case FormatText, FormatJSON, FormatYAML, FormatCSV:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unsupported format %q (expected text, json, yaml or csv)", value)
+	}
+}
+
+// SetFormat switches the output mode for the whole process. Any structured
+// format disables colors and suppresses decorative output so that stdout
+// carries only the document written by Emit; warnings and errors go to stderr.
+func SetFormat(f Format) {
+	currentFormat = f
+	if f != FormatText {
+		color.NoColor = true
+	}
+}
+
+// CurrentFormat returns the active output format.
+func CurrentFormat() Format { return currentFormat }
+
+// Structured reports whether a machine-readable format is active.
+func Structured() bool { return currentFormat != FormatText }
+
+// Tabular is implemented by results that have a natural CSV layout.
+// Results that do not implement it are flattened into dotted column names.
+type Tabular interface {
+	Table() (headers []string, rows [][]string)
+}
+
+// Emit writes v to stdout in the active structured format. Field names come
+// from the `json`/`yaml` struct tags, which form the stable scripting interface.
+// In text mode Emit does nothing, as commands render their own text output.
+func Emit(v any) error {
+	switch currentFormat {
+	case FormatJSON:
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		return enc.Encode(v)
+	case FormatYAML:
+		enc := yaml.NewEncoder(out)
+		enc.SetIndent(2)
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		return enc.Close()
+	case FormatCSV:
+		return emitCSV(v)
+	default:
+		return nil
+	}
+}
+
+func emitCSV(v any) error {
+	var headers []string
+	var rows [][]string
+
+	if t, ok := v.(Tabular); ok {
+		headers, rows = t.Table()
+	} else {
+		var err error
+		headers, rows, err = flattenForCSV(v)
+		if err != nil {
+			return err
+		}
+	}
+
+	w := csv.NewWriter(out)
+	if err := w.Write(headers); err != nil {
+		return err
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return err
+	}
+	return w.Error()
+}
+
+// flattenForCSV turns a value into CSV columns via its JSON form: a list
+// becomes one row per element, anything else a single row. Nested objects
+// are flattened into dotted names; nested lists are kept as JSON text.
+func flattenForCSV(v any) ([]string, [][]string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, nil, err
+	}
+	var generic any
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, nil, err
+	}
+
+	items, ok := generic.([]any)
+	if !ok {
+		items = []any{generic}
+	}
+
+	var flat []map[string]string
+	columns := map[string]bool{}
+	for _, item := range items {
+		m := map[string]string{}
+		flattenValue("", item, m)
+		for k := range m {
+			columns[k] = true
+		}
+		flat = append(flat, m)
+	}
+
+	headers := make([]string, 0, len(columns))
+	for k := range columns {
+		headers = append(headers, k)
+	}
+	sort.Strings(headers)
+
+	rows := make([][]string, 0, len(flat))
+	for _, m := range flat {
+		row := make([]string, len(headers))
+		for i, h := range headers {
+			row[i] = m[h]
+		}
+		rows = append(rows, row)
+	}
+	return headers, rows, nil
+}
+
+func flattenValue(prefix string, v any, into map[string]string) {
+	key := prefix
+	if key == "" {
+		key = "value"
+	}
+	switch val := v.(type) {
+	case map[string]any:
+		for k, child := range val {
+			name := k
+			if prefix != "" {
+				name = prefix + "." + k
+			}
+			flattenValue(name, child, into)
+		}
+	case []any:
+		data, _ := json.Marshal(val)
+		into[key] = string(data)
+	case nil:
+		into[key] = ""
+	case string:
+		into[key] = val
+	default:
+		into[key] = fmt.Sprint(val)
+	}
+}
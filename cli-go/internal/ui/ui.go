@@ -2,7 +2,7 @@ package ui
 
 import (
 	"fmt"
-	"os"
+	"math"
 	"strings"
 	"text/tabwriter"
 
@@ -21,23 +21,57 @@ var (
 
 // Section prints a prominent section header.
 func Section(title string) {
+	if Structured() {
+		return
+	}
 	sep := strings.Repeat("─", len(title)+2)
-	fmt.Printf("\n%s\n%s %s\n%s\n", color.HiCyanString(sep), color.HiCyanString("▶"), Title(title), color.HiCyanString(sep))
+	fmt.Fprintf(out, "\n%s\n%s %s\n%s\n", color.HiCyanString(sep), color.HiCyanString("▶"), Title(title), color.HiCyanString(sep))
 }
 
 // Subsection prints a smaller subsection header.
 func Subsection(title string) {
-	fmt.Printf("\n%s %s\n", color.HiCyanString("→"), Subtitle(title))
+	if Structured() {
+		return
+	}
+	fmt.Fprintf(out, "\n%s %s\n", color.HiCyanString("→"), Subtitle(title))
 }
 
-// Simple status helpers
+// Simple status helpers. In structured output mode, progress messages are
+// suppressed and warnings/errors are written to stderr without icons.
 func Successf(format string, a ...any) {
-	fmt.Printf("%s %s\n", SuccessTxt("✓"), fmt.Sprintf(format, a...))
+	if Structured() {
+		return
+	}
+	fmt.Fprintf(out, "%s %s\n", SuccessTxt("✓"), fmt.Sprintf(format, a...))
+}
+func Infof(format string, a ...any) {
+	if Structured() {
+		return
+	}
+	fmt.Fprintf(out, "%s %s\n", InfoTxt("i"), fmt.Sprintf(format, a...))
+}
+func Warnf(format string, a ...any) {
+	if Structured() {
+		fmt.Fprintf(errOut, "warning: %s\n", fmt.Sprintf(format, a...))
+		return
+	}
+	fmt.Fprintf(out, "%s %s\n", WarnTxt("!"), fmt.Sprintf(format, a...))
 }
-func Infof(format string, a ...any) { fmt.Printf("%s %s\n", InfoTxt("i"), fmt.Sprintf(format, a...)) }
-func Warnf(format string, a ...any) { fmt.Printf("%s %s\n", WarnTxt("!"), fmt.Sprintf(format, a...)) }
 func Errorf(format string, a ...any) {
-	fmt.Printf("%s %s\n", ErrorTxt("✗"), fmt.Sprintf(format, a...))
+	if Structured() {
+		fmt.Fprintf(errOut, "error: %s\n", fmt.Sprintf(format, a...))
+		return
+	}
+	fmt.Fprintf(out, "%s %s\n", ErrorTxt("✗"), fmt.Sprintf(format, a...))
+}
+
+// Printf prints plain text such as a continuation line. It is suppressed in
+// structured output mode, like the other progress helpers.
+func Printf(format string, a ...any) {
+	if Structured() {
+		return
+	}
+	fmt.Fprintf(out, format, a...)
 }
 
 // Badge returns a colored status string like ✅/❌/❓ with optional text.
@@ -63,7 +97,10 @@ func Badge(ok bool, unknown bool, text string) string {
 
 // PrintKV renders a two-column key/value list aligned nicely.
 func PrintKV(pairs [][2]string) {
-	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if Structured() {
+		return
+	}
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
 	for _, kv := range pairs {
 		fmt.Fprintf(tw, "%s:\t%s\n", Subtitle(kv[0]), kv[1])
 	}
@@ -72,7 +109,10 @@ func PrintKV(pairs [][2]string) {
 
 // PrintTable renders a generic table using tabwriter.
 func PrintTable(headers []string, rows [][]string) {
-	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if Structured() {
+		return
+	}
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
 	// header
 	for i, h := range headers {
 		if i > 0 {
@@ -101,3 +141,86 @@ func PrintTable(headers []string, rows [][]string) {
 	}
 	_ = tw.Flush()
 }
+
+// sparkTicks are the block characters used by Sparkline, lowest to highest.
+var sparkTicks = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline renders values as a compact one-line bar chart scaled between
+// their minimum and maximum. NaN values are rendered as blanks.
+func Sparkline(values []float64) string {
+	min, max := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		if math.IsNaN(v) {
+			continue
+		}
+		min = math.Min(min, v)
+		max = math.Max(max, v)
+	}
+
+	var sb strings.Builder
+	for _, v := range values {
+		switch {
+		case math.IsNaN(v):
+			sb.WriteRune(' ')
+		case max == min:
+			sb.WriteRune(sparkTicks[len(sparkTicks)/2])
+		default:
+			idx := int((v - min) / (max - min) * float64(len(sparkTicks)-1))
+			sb.WriteRune(sparkTicks[idx])
+		}
+	}
+	return InfoTxt(sb.String())
+}
+
+// Bar is a single labelled row of a bar chart.
+type Bar struct {
+	Label string
+	Value float64
+	Note  string // printed after the bar, e.g. "42 (12.5%)"
+}
+
+// PrintBarChart renders horizontal bars scaled so that the largest value spans width cells.
+func PrintBarChart(bars []Bar, width int) {
+	if Structured() {
+		return
+	}
+	max := 0.0
+	for _, b := range bars {
+		max = math.Max(max, b.Value)
+	}
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for _, b := range bars {
+		n := 0
+		if max > 0 {
+			n = int(math.Round(b.Value / max * float64(width)))
+		}
+		if n == 0 && b.Value > 0 {
+			n = 1
+		}
+		fmt.Fprintf(tw, "%s\t%s %s\n", Subtitle(b.Label), InfoTxt(strings.Repeat("█", n)), b.Note)
+	}
+	_ = tw.Flush()
+}
+
+// PrintDiff prints a unified diff with added lines in green, removed lines in
+// red and hunk headers in cyan.
+func PrintDiff(diff string) {
+	if Structured() {
+		return
+	}
+	for _, line := range strings.SplitAfter(diff, "\n") {
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			fmt.Fprint(out, Subtitle(line))
+		case strings.HasPrefix(line, "@@"):
+			fmt.Fprint(out, color.CyanString("%s", line))
+		case strings.HasPrefix(line, "+"):
+			fmt.Fprint(out, SuccessTxt(line))
+		case strings.HasPrefix(line, "-"):
+			fmt.Fprint(out, ErrorTxt(line))
+		default:
+			fmt.Fprint(out, line)
+		}
+	}
+}